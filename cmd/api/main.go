@@ -1,14 +1,94 @@
 package main
 
 import (
+	"cambio-brl-usd/config"
 	"cambio-brl-usd/handlers"
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/rastreamento"
+	"cambio-brl-usd/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	r := gin.Default()
-	r.GET("/cotacao/ultima", handlers.UltimaCotacao)
-	r.GET("/cotacao/historico", handlers.HistoricoCotacao)
-	r.Run(":8080")
+	cfg, err := config.Carregar()
+	if err != nil {
+		logs.Fatal("erro ao carregar a configuração", err)
+	}
+	if err := logs.Configurar(cfg.Log.Nivel); err != nil {
+		logs.Fatal("nível de log inválido", err)
+	}
+	// SIGTERM (enviado pelo ECS ou pelo Kubernetes) e Ctrl+C encerram o
+	// servidor depois de atender as requisições em andamento e de exportar os
+	// spans pendentes.
+	ctx, parar := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer parar()
+	rastros, err := rastreamento.Configurar(ctx, cfg.Rastreamento.Exportador)
+	if err != nil {
+		logs.Fatal("erro ao configurar o rastreamento", err)
+	}
+
+	// Configuração e clientes da AWS são criados uma única vez e
+	// compartilhados por todas as requisições.
+	clientes, err := services.NovosClientesAWS(ctx, cfg.AWS)
+	if err != nil {
+		logs.Fatal("erro ao criar os clientes da AWS", err)
+	}
+	repo, err := services.NovoRepositorio(cfg.Armazenamento, clientes.DynamoDB)
+	if err != nil {
+		logs.Fatal("erro ao abrir o armazenamento", err)
+	}
+	// A chave do Fixer fica em cache e é relida em segundo plano, para que
+	// rotações do segredo valham sem reiniciar a API.
+	fonte, err := services.NovoSecretSource(cfg.Segredo, clientes)
+	if err != nil {
+		logs.Fatal("erro ao configurar o segredo do Fixer", err)
+	}
+	segredos := services.NovoCacheSegredo(fonte, time.Duration(cfg.Segredo.TTL), time.Now)
+	segredos.Prazo = time.Duration(cfg.Prazos.Segredo)
+	if cfg.Segredo.Renovacao > 0 {
+		segredos.IniciarRenovacao(ctx, time.Duration(cfg.Segredo.Renovacao))
+	}
+	svc, err := services.NovoCotacaoService(cfg, rastreamento.ClienteHTTP(), repo, segredos, time.Now)
+	if err != nil {
+		logs.Fatal("erro ao criar o serviço de cotações", err)
+	}
+
+	// Os handlers repassam o contexto de cada requisição ao serviço: quando o
+	// cliente desconecta, as consultas ao banco e aos provedores param. O
+	// contexto leva também o ID de correlação usado em todos os logs e o span
+	// da requisição.
+	r := gin.New()
+	r.Use(gin.Recovery(), handlers.Rastrear(), handlers.IDRequisicao(), handlers.RegistrarAcesso(), handlers.Medir())
+	handlers.NovoCotacaoHandler(svc).Registrar(r)
+	r.GET("/metrics", gin.WrapH(metricas.Handler()))
+
+	servidor := &http.Server{Addr: cfg.Servidor.Endereco(), Handler: r}
+	erros := make(chan error, 1)
+	go func() { erros <- servidor.ListenAndServe() }()
+	slog.Info("servidor iniciado", "endereco", cfg.Servidor.Endereco())
+
+	select {
+	case err := <-erros:
+		logs.Fatal("erro no servidor HTTP", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("encerrando o servidor")
+	encerrar, cancelar := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelar()
+	if err := servidor.Shutdown(encerrar); err != nil {
+		slog.Error("erro ao encerrar o servidor HTTP", "erro", err)
+	}
+	if err := rastros.Encerrar(encerrar); err != nil {
+		slog.Error("erro ao exportar os últimos spans", "erro", err)
+	}
 }
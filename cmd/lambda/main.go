@@ -1,17 +1,90 @@
 package main
 
 import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/rastreamento"
 	"cambio-brl-usd/services"
 	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func handler(ctx context.Context) (string, error) {
-	services.BuscarUltimaCotacao()
-	return "Ultima cotação buscada com sucesso!", nil
+// novoHandler cria o handler da Lambda, agendada pelo EventBridge, que busca
+// nos provedores e grava a cotação da moeda pivô para cada uma das demais
+// moedas permitidas. É a Lambda que alimenta o banco lido por /cotacao/ultima.
+// O contexto da invocação, com o prazo da Lambda, é repassado ao serviço, e o
+// ID da requisição da AWS é o ID de correlação dos logs da invocação. Cada
+// invocação é um trace, exportado antes de a Lambda ser congelada.
+func novoHandler(svc *services.CotacaoService, rastros *rastreamento.Provedor) func(context.Context) (string, error) {
+	return func(ctx context.Context) (resposta string, err error) {
+		var atributos []attribute.KeyValue
+		if lc, ok := lambdacontext.FromContext(ctx); ok {
+			ctx = logs.ComID(ctx, lc.AwsRequestID)
+			atributos = append(atributos, semconv.FaaSInvocationID(lc.AwsRequestID))
+		}
+		ctx, span := rastreamento.Iniciar(ctx, "atualizar cotações", trace.WithAttributes(atributos...))
+		defer func() {
+			rastreamento.Finalizar(span, err)
+			if err := rastros.Descarregar(ctx); err != nil {
+				slog.WarnContext(ctx, "erro ao exportar os spans da invocação", "erro", err)
+			}
+		}()
+
+		pivo := svc.Config().Moedas.Pivo
+		cotacoes, err := svc.AtualizarCotacoes(ctx, pivo, svc.DestinosPadrao(pivo))
+		if err != nil {
+			slog.ErrorContext(ctx, "erro ao atualizar as cotações", "origem", pivo, "erro", err)
+			return "", err
+		}
+		slog.InfoContext(ctx, "cotações atualizadas", "origem", pivo, "quantidade", len(cotacoes))
+		return fmt.Sprintf("%d cotações atualizadas com sucesso!", len(cotacoes)), nil
+	}
 }
 
 func main() {
-	lambda.Start(handler)
+	cfg, err := config.Carregar()
+	if err != nil {
+		logs.Fatal("erro ao carregar a configuração", err)
+	}
+	if err := logs.Configurar(cfg.Log.Nivel); err != nil {
+		logs.Fatal("nível de log inválido", err)
+	}
+	rastros, err := rastreamento.Configurar(context.Background(), cfg.Rastreamento.Exportador)
+	if err != nil {
+		logs.Fatal("erro ao configurar o rastreamento", err)
+	}
+
+	// Configuração e clientes da AWS são criados uma vez por cold start e
+	// reaproveitados nas invocações seguintes.
+	clientes, err := services.NovosClientesAWS(context.Background(), cfg.AWS)
+	if err != nil {
+		logs.Fatal("erro ao criar os clientes da AWS", err)
+	}
+	repo, err := services.NovoRepositorio(cfg.Armazenamento, clientes.DynamoDB)
+	if err != nil {
+		logs.Fatal("erro ao abrir o armazenamento", err)
+	}
+	// A chave do Fixer fica em cache entre invocações pelo TTL configurado.
+	// Não há renovação em segundo plano: a Lambda fica congelada entre
+	// invocações.
+	fonte, err := services.NovoSecretSource(cfg.Segredo, clientes)
+	if err != nil {
+		logs.Fatal("erro ao configurar o segredo do Fixer", err)
+	}
+	segredos := services.NovoCacheSegredo(fonte, time.Duration(cfg.Segredo.TTL), time.Now)
+	segredos.Prazo = time.Duration(cfg.Prazos.Segredo)
+	svc, err := services.NovoCotacaoService(cfg, rastreamento.ClienteHTTP(), repo, segredos, time.Now)
+	if err != nil {
+		logs.Fatal("erro ao criar o serviço de cotações", err)
+	}
+
+	lambda.Start(novoHandler(svc, rastros))
 }
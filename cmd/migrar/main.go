@@ -0,0 +1,50 @@
+// Comando migrar copia as cotações da tabela original do DynamoDB (chave só
+// data_hora) para a tabela particionada por par de moedas. Pode ser executado
+// mais de uma vez: itens que já existem no destino são ignorados, sem
+// alteração.
+//
+//	go run ./cmd/migrar -origem Cotacoes -destino CotacoesPorPar
+package main
+
+import (
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/repository"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	origem := flag.String("origem", repository.TabelaLegada, "tabela de onde as cotações são lidas")
+	destino := flag.String("destino", repository.TabelaPadrao, "tabela para onde as cotações são copiadas")
+	regiao := flag.String("regiao", "us-east-1", "região da AWS")
+	nivel := flag.String("log", "info", "nível de log: debug, info, warn ou error")
+	flag.Parse()
+
+	if err := logs.Configurar(*nivel); err != nil {
+		logs.Fatal("nível de log inválido", err)
+	}
+
+	// Ctrl+C interrompe a migração entre uma chamada e outra ao DynamoDB
+	ctx, parar := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer parar()
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(*regiao))
+	if err != nil {
+		logs.Fatal("erro ao carregar a configuração da AWS", err)
+	}
+
+	client := dynamodb.NewFromConfig(cfg)
+	copiadas, ignoradas, err := repository.MigrarTabelaLegada(ctx, client, *origem, repository.NovoDynamoRepository(client, *destino))
+	if err != nil {
+		slog.Error("migração interrompida", "origem", *origem, "destino", *destino, "copiadas", copiadas, "ignoradas", ignoradas, "erro", err)
+		os.Exit(1)
+	}
+
+	slog.Info("migração concluída", "origem", *origem, "destino", *destino, "copiadas", copiadas, "ignoradas", ignoradas)
+}
@@ -0,0 +1,549 @@
+// Package config reúne as configurações da API em uma struct tipada, lida de
+// valores padrão, de um arquivo YAML ou JSON opcional e de variáveis de
+// ambiente, nessa ordem de precedência, e validada na inicialização.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // fusos horários embutidos; a imagem do contêiner não tem zoneinfo
+
+	"gopkg.in/yaml.v3"
+)
+
+// Valores padrão dos provedores de cotações.
+const (
+	FixerURLPadrao            = "https://api.apilayer.com/fixer"
+	BCBURLPadrao              = "https://olinda.bcb.gov.br/olinda/servico/PTAX/versao/v1/odata"
+	ExchangeRateHostURLPadrao = "https://api.exchangerate.host"
+)
+
+// Config é a configuração completa da API.
+type Config struct {
+	Servidor      Servidor      `yaml:"servidor" json:"servidor"`
+	AWS           AWS           `yaml:"aws" json:"aws"`
+	Armazenamento Armazenamento `yaml:"armazenamento" json:"armazenamento"`
+	Segredo       Segredo       `yaml:"segredo" json:"segredo"`
+	Provedores    Provedores    `yaml:"provedores" json:"provedores"`
+	Moedas        Moedas        `yaml:"moedas" json:"moedas"`
+	Cotacao       Cotacao       `yaml:"cotacao" json:"cotacao"`
+	Conversao     Conversao     `yaml:"conversao" json:"conversao"`
+	Historico     Historico     `yaml:"historico" json:"historico"`
+	Prazos        Prazos        `yaml:"prazos" json:"prazos"`
+	Prontidao     Prontidao     `yaml:"prontidao" json:"prontidao"`
+	Log           Log           `yaml:"log" json:"log"`
+	Rastreamento  Rastreamento  `yaml:"rastreamento" json:"rastreamento"`
+}
+
+// Servidor configura o servidor HTTP de cmd/api. TokenAtualizacao é o token
+// Bearer exigido por POST /cotacao/atualizar; vazio desativa a rota.
+type Servidor struct {
+	Porta            int    `yaml:"porta" json:"porta"`                         // PORT
+	TokenAtualizacao string `yaml:"token_atualizacao" json:"token_atualizacao"` // ATUALIZACAO_TOKEN
+}
+
+// Endereco é o endereço em que o Gin escuta (":8080").
+func (s Servidor) Endereco() string {
+	return ":" + strconv.Itoa(s.Porta)
+}
+
+// AWS configura o acesso aos serviços da AWS.
+type AWS struct {
+	Regiao string `yaml:"regiao" json:"regiao"` // AWS_REGION
+}
+
+// Armazenamento escolhe onde as cotações são gravadas.
+type Armazenamento struct {
+	Backend       string `yaml:"backend" json:"backend"`               // STORAGE_BACKEND: dynamodb, memory ou sqlite
+	TabelaDynamo  string `yaml:"tabela_dynamo" json:"tabela_dynamo"`   // DYNAMODB_TABLE
+	CaminhoSQLite string `yaml:"caminho_sqlite" json:"caminho_sqlite"` // SQLITE_PATH
+}
+
+// Segredo identifica onde está a chave do Fixer e por quanto tempo ela fica
+// em cache. Fonte escolhe de onde ler: "secretsmanager" e "ssm" usam Nome,
+// "env" usa Variavel e "file" usa Arquivo. Com ChaveJSON preenchida o valor
+// lido é um JSON e a chave é esse campo; vazia, o Secrets Manager usa o campo
+// fixer_api_key e as demais fontes usam o valor inteiro.
+//
+// Com Renovacao maior que zero a chave é relida em segundo plano nesse
+// intervalo; zero desliga a renovação e a chave só é relida quando o TTL vence
+// ou o Fixer a rejeita.
+type Segredo struct {
+	Fonte     string  `yaml:"fonte" json:"fonte"`           // FIXER_SECRET_SOURCE
+	Nome      string  `yaml:"nome" json:"nome"`             // FIXER_SECRET_NAME
+	Variavel  string  `yaml:"variavel" json:"variavel"`     // FIXER_SECRET_ENV
+	Arquivo   string  `yaml:"arquivo" json:"arquivo"`       // FIXER_SECRET_FILE
+	ChaveJSON string  `yaml:"chave_json" json:"chave_json"` // FIXER_SECRET_JSON_KEY
+	TTL       Duracao `yaml:"ttl" json:"ttl"`               // FIXER_SECRET_TTL
+	Renovacao Duracao `yaml:"renovacao" json:"renovacao"`   // FIXER_SECRET_REFRESH
+}
+
+// Provedores define a cadeia de provedores de cotações, seus endereços e como
+// as requisições a cada um são feitas. Timeout vale para cada tentativa; uma
+// requisição que falha por erro de rede, 5xx ou 429 é repetida até Tentativas
+// vezes, com espera aleatória que dobra a partir de EsperaInicial até
+// EsperaMaxima. Depois de FalhasCircuito requisições seguidas com falha o
+// provedor deixa de ser chamado por PausaCircuito; FalhasCircuito 0 desliga
+// o circuito.
+type Provedores struct {
+	Ordem                 []string `yaml:"ordem" json:"ordem"`                                     // RATE_PROVIDERS
+	FixerURL              string   `yaml:"fixer_url" json:"fixer_url"`                             // FIXER_API_URL
+	BCBURL                string   `yaml:"bcb_url" json:"bcb_url"`                                 // BCB_API_URL
+	ExchangeRateURL       string   `yaml:"exchangerate_url" json:"exchangerate_url"`               // EXCHANGERATE_API_URL
+	ExchangeRateAccessKey string   `yaml:"exchangerate_access_key" json:"exchangerate_access_key"` // EXCHANGERATE_ACCESS_KEY
+
+	Timeout        Duracao `yaml:"timeout" json:"timeout"`                 // PROVIDER_TIMEOUT
+	Tentativas     int     `yaml:"tentativas" json:"tentativas"`           // PROVIDER_MAX_ATTEMPTS
+	EsperaInicial  Duracao `yaml:"espera_inicial" json:"espera_inicial"`   // PROVIDER_BACKOFF
+	EsperaMaxima   Duracao `yaml:"espera_maxima" json:"espera_maxima"`     // PROVIDER_BACKOFF_MAX
+	FalhasCircuito int     `yaml:"falhas_circuito" json:"falhas_circuito"` // PROVIDER_BREAKER_FAILURES
+	PausaCircuito  Duracao `yaml:"pausa_circuito" json:"pausa_circuito"`   // PROVIDER_BREAKER_COOLDOWN
+}
+
+// Moedas define as moedas aceitas e a moeda pivô das taxas cruzadas.
+type Moedas struct {
+	Permitidas []string `yaml:"permitidas" json:"permitidas"` // MOEDAS_PERMITIDAS
+	Pivo       string   `yaml:"pivo" json:"pivo"`             // MOEDA_PIVO
+}
+
+// Cotacao define a precisão das cotações gravadas, por quanto tempo a última
+// cotação obtida dos provedores é reaproveitada da memória pela atualização
+// (zero desliga o cache) e há quanto tempo sem ser obtida por uma atualização
+// a cotação salva passa a ser marcada como desatualizada por /cotacao/ultima
+// (zero nunca marca). O padrão de IdadeMaxima acompanha o agendamento da
+// Lambda em terraform/eventbridge.tf, que roda às 8h, 14h e 20h UTC: o maior
+// intervalo entre execuções é de 12h, e 13h só é ultrapassado quando alguma
+// atualização falha. Mudar o agendamento exige rever o padrão.
+type Cotacao struct {
+	CasasDecimais int     `yaml:"casas_decimais" json:"casas_decimais"` // COTACAO_CASAS_DECIMAIS
+	Validade      Duracao `yaml:"validade" json:"validade"`             // COTACAO_VALIDADE
+	IdadeMaxima   Duracao `yaml:"idade_maxima" json:"idade_maxima"`     // COTACAO_IDADE_MAXIMA
+}
+
+// Conversao define o arredondamento do valor convertido por /conversao.
+type Conversao struct {
+	CasasDecimais  int    `yaml:"casas_decimais" json:"casas_decimais"` // CONVERSAO_CASAS_DECIMAIS
+	Arredondamento string `yaml:"arredondamento" json:"arredondamento"` // CONVERSAO_ARREDONDAMENTO
+}
+
+// Historico define o formato das datas recebidas pelos endpoints de histórico,
+// a paginação e o fuso padrão dos candles.
+type Historico struct {
+	LayoutData   string `yaml:"layout_data" json:"layout_data"`     // HISTORICO_LAYOUT_DATA
+	LimitePadrao int    `yaml:"limite_padrao" json:"limite_padrao"` // HISTORICO_LIMITE_PADRAO
+	LimiteMaximo int    `yaml:"limite_maximo" json:"limite_maximo"` // HISTORICO_LIMITE_MAXIMO
+	Fuso         string `yaml:"fuso" json:"fuso"`                   // HISTORICO_FUSO
+}
+
+// Duracao é um time.Duration escrito como texto ("90s", "5m") no arquivo de
+// configuração e nas variáveis de ambiente.
+type Duracao time.Duration
+
+func (d *Duracao) UnmarshalText(texto []byte) error {
+	valor, err := time.ParseDuration(string(texto))
+	if err != nil {
+		return err
+	}
+	*d = Duracao(valor)
+	return nil
+}
+
+func (d Duracao) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d Duracao) String() string { return time.Duration(d).String() }
+
+// Valores aceitos em Armazenamento.Backend, Segredo.Fonte, Provedores.Ordem
+// e Conversao.Arredondamento.
+var (
+	Backends        = []string{"dynamodb", "memory", "sqlite"}
+	FontesSegredo   = []string{"secretsmanager", "ssm", "env", "file"}
+	NomesProvedores = []string{"fixer", "bcb", "exchangeratehost"}
+	Arredondamentos = []string{"half_even", "half_up", "down", "up"}
+	NiveisLog       = []string{"debug", "info", "warn", "error"}
+	Exportadores    = []string{"none", "stdout", "otlp"}
+)
+
+// Prazos limita quanto cada operação pode levar, além do prazo de quem a
+// pediu (a requisição HTTP ou a execução da Lambda): Armazenamento vale para
+// cada leitura ou gravação no banco, Segredo para cada leitura da chave do
+// Fixer e Atualizacao para toda a consulta aos provedores com a gravação das
+// cotações.
+type Prazos struct {
+	Armazenamento Duracao `yaml:"armazenamento" json:"armazenamento"` // STORAGE_TIMEOUT
+	Segredo       Duracao `yaml:"segredo" json:"segredo"`             // SECRET_TIMEOUT
+	Atualizacao   Duracao `yaml:"atualizacao" json:"atualizacao"`     // UPDATE_TIMEOUT
+}
+
+// Prontidao configura as verificações de /readyz. A ingestão é dada como
+// parada quando nenhuma atualização obtém cotações da moeda pivô há mais de
+// IdadeMaximaIngestao (zero não verifica a idade). O padrão segue o de
+// Cotacao.IdadeMaxima; veja lá a relação com o agendamento da Lambda.
+type Prontidao struct {
+	IdadeMaximaIngestao Duracao `yaml:"idade_maxima_ingestao" json:"idade_maxima_ingestao"` // READINESS_MAX_INGESTION_AGE
+}
+
+// Log configura os logs em JSON: só as mensagens a partir de Nivel são
+// escritas.
+type Log struct {
+	Nivel string `yaml:"nivel" json:"nivel"` // LOG_LEVEL
+}
+
+// Rastreamento escolhe para onde vão os spans do OpenTelemetry: "none" não
+// exporta, "stdout" escreve na saída padrão e "otlp" envia ao coletor das
+// variáveis OTEL_EXPORTER_OTLP_* padrão do OpenTelemetry.
+type Rastreamento struct {
+	Exportador string `yaml:"exportador" json:"exportador"` // OTEL_TRACES_EXPORTER
+}
+
+// Padrao retorna a configuração usada quando nada é informado.
+func Padrao() Config {
+	return Config{
+		Servidor:      Servidor{Porta: 8080},
+		AWS:           AWS{Regiao: "us-east-1"},
+		Armazenamento: Armazenamento{Backend: "dynamodb", TabelaDynamo: "CotacoesPorPar", CaminhoSQLite: "cotacoes.db"},
+		Segredo: Segredo{
+			Fonte:     "secretsmanager",
+			Nome:      "fixer-api-key-dev",
+			Variavel:  "FIXER_API_KEY",
+			TTL:       Duracao(15 * time.Minute),
+			Renovacao: Duracao(5 * time.Minute),
+		},
+		Provedores: Provedores{
+			Ordem:           []string{"fixer", "bcb"},
+			FixerURL:        FixerURLPadrao,
+			BCBURL:          BCBURLPadrao,
+			ExchangeRateURL: ExchangeRateHostURLPadrao,
+			Timeout:         Duracao(10 * time.Second),
+			Tentativas:      3,
+			EsperaInicial:   Duracao(200 * time.Millisecond),
+			EsperaMaxima:    Duracao(5 * time.Second),
+			FalhasCircuito:  5,
+			PausaCircuito:   Duracao(30 * time.Second),
+		},
+		Moedas:       Moedas{Permitidas: []string{"BRL", "USD", "EUR", "GBP", "ARS", "JPY"}, Pivo: "BRL"},
+		Cotacao:      Cotacao{CasasDecimais: 8, Validade: Duracao(time.Minute), IdadeMaxima: Duracao(13 * time.Hour)},
+		Conversao:    Conversao{CasasDecimais: 2, Arredondamento: "half_even"},
+		Historico:    Historico{LayoutData: "2006-01-02T15:04", LimitePadrao: 100, LimiteMaximo: 1000, Fuso: "America/Sao_Paulo"},
+		Prazos:       Prazos{Armazenamento: Duracao(5 * time.Second), Segredo: Duracao(5 * time.Second), Atualizacao: Duracao(45 * time.Second)},
+		Prontidao:    Prontidao{IdadeMaximaIngestao: Duracao(13 * time.Hour)},
+		Log:          Log{Nivel: "info"},
+		Rastreamento: Rastreamento{Exportador: "none"},
+	}
+}
+
+// Carregar monta a configuração a partir de Padrao, do arquivo indicado em
+// CONFIG_FILE (se houver) e das variáveis de ambiente, e a valida.
+func Carregar() (Config, error) {
+	cfg := Padrao()
+
+	if caminho := os.Getenv("CONFIG_FILE"); caminho != "" {
+		if err := cfg.lerArquivo(caminho); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.lerAmbiente(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validar(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// lerArquivo sobrepõe cfg com os campos presentes no arquivo. O formato vem
+// da extensão: .yaml, .yml ou .json.
+func (cfg *Config) lerArquivo(caminho string) error {
+	dados, err := os.ReadFile(caminho)
+	if err != nil {
+		return fmt.Errorf("erro ao ler arquivo de configuração: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(caminho)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(dados, cfg)
+	case ".json":
+		err = json.Unmarshal(dados, cfg)
+	default:
+		return fmt.Errorf("arquivo de configuração %s: extensão não suportada (use .yaml, .yml ou .json)", caminho)
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao interpretar arquivo de configuração %s: %w", caminho, err)
+	}
+	return nil
+}
+
+// lerAmbiente sobrepõe cfg com as variáveis de ambiente definidas.
+func (cfg *Config) lerAmbiente() error {
+	var erros []error
+
+	texto := func(nome string, destino *string) {
+		if valor := strings.TrimSpace(os.Getenv(nome)); valor != "" {
+			*destino = valor
+		}
+	}
+	lista := func(nome string, destino *[]string) {
+		if valor := strings.TrimSpace(os.Getenv(nome)); valor != "" {
+			*destino = strings.Split(valor, ",")
+		}
+	}
+	inteiro := func(nome string, destino *int) {
+		valor := strings.TrimSpace(os.Getenv(nome))
+		if valor == "" {
+			return
+		}
+		n, err := strconv.Atoi(valor)
+		if err != nil {
+			erros = append(erros, fmt.Errorf("%s: %q não é um número inteiro", nome, valor))
+			return
+		}
+		*destino = n
+	}
+	duracao := func(nome string, destino *Duracao) {
+		valor := strings.TrimSpace(os.Getenv(nome))
+		if valor == "" {
+			return
+		}
+		if err := destino.UnmarshalText([]byte(valor)); err != nil {
+			erros = append(erros, fmt.Errorf("%s: %q não é uma duração (use, por exemplo, 90s ou 5m)", nome, valor))
+		}
+	}
+
+	inteiro("PORT", &cfg.Servidor.Porta)
+	texto("ATUALIZACAO_TOKEN", &cfg.Servidor.TokenAtualizacao)
+	texto("AWS_REGION", &cfg.AWS.Regiao)
+	texto("STORAGE_BACKEND", &cfg.Armazenamento.Backend)
+	texto("DYNAMODB_TABLE", &cfg.Armazenamento.TabelaDynamo)
+	texto("SQLITE_PATH", &cfg.Armazenamento.CaminhoSQLite)
+	texto("FIXER_SECRET_SOURCE", &cfg.Segredo.Fonte)
+	texto("FIXER_SECRET_NAME", &cfg.Segredo.Nome)
+	texto("FIXER_SECRET_ENV", &cfg.Segredo.Variavel)
+	texto("FIXER_SECRET_FILE", &cfg.Segredo.Arquivo)
+	texto("FIXER_SECRET_JSON_KEY", &cfg.Segredo.ChaveJSON)
+	duracao("FIXER_SECRET_TTL", &cfg.Segredo.TTL)
+	duracao("FIXER_SECRET_REFRESH", &cfg.Segredo.Renovacao)
+	lista("RATE_PROVIDER", &cfg.Provedores.Ordem) // nome antigo, de um único provedor
+	lista("RATE_PROVIDERS", &cfg.Provedores.Ordem)
+	texto("FIXER_API_URL", &cfg.Provedores.FixerURL)
+	texto("BCB_API_URL", &cfg.Provedores.BCBURL)
+	texto("EXCHANGERATE_API_URL", &cfg.Provedores.ExchangeRateURL)
+	texto("EXCHANGERATE_ACCESS_KEY", &cfg.Provedores.ExchangeRateAccessKey)
+	duracao("PROVIDER_TIMEOUT", &cfg.Provedores.Timeout)
+	inteiro("PROVIDER_MAX_ATTEMPTS", &cfg.Provedores.Tentativas)
+	duracao("PROVIDER_BACKOFF", &cfg.Provedores.EsperaInicial)
+	duracao("PROVIDER_BACKOFF_MAX", &cfg.Provedores.EsperaMaxima)
+	inteiro("PROVIDER_BREAKER_FAILURES", &cfg.Provedores.FalhasCircuito)
+	duracao("PROVIDER_BREAKER_COOLDOWN", &cfg.Provedores.PausaCircuito)
+	lista("MOEDAS_PERMITIDAS", &cfg.Moedas.Permitidas)
+	texto("MOEDA_PIVO", &cfg.Moedas.Pivo)
+	inteiro("COTACAO_CASAS_DECIMAIS", &cfg.Cotacao.CasasDecimais)
+	duracao("COTACAO_VALIDADE", &cfg.Cotacao.Validade)
+	duracao("COTACAO_IDADE_MAXIMA", &cfg.Cotacao.IdadeMaxima)
+	inteiro("CONVERSAO_CASAS_DECIMAIS", &cfg.Conversao.CasasDecimais)
+	texto("CONVERSAO_ARREDONDAMENTO", &cfg.Conversao.Arredondamento)
+	texto("HISTORICO_LAYOUT_DATA", &cfg.Historico.LayoutData)
+	inteiro("HISTORICO_LIMITE_PADRAO", &cfg.Historico.LimitePadrao)
+	inteiro("HISTORICO_LIMITE_MAXIMO", &cfg.Historico.LimiteMaximo)
+	texto("HISTORICO_FUSO", &cfg.Historico.Fuso)
+	duracao("STORAGE_TIMEOUT", &cfg.Prazos.Armazenamento)
+	duracao("SECRET_TIMEOUT", &cfg.Prazos.Segredo)
+	duracao("UPDATE_TIMEOUT", &cfg.Prazos.Atualizacao)
+	duracao("READINESS_MAX_INGESTION_AGE", &cfg.Prontidao.IdadeMaximaIngestao)
+	texto("LOG_LEVEL", &cfg.Log.Nivel)
+	texto("OTEL_TRACES_EXPORTER", &cfg.Rastreamento.Exportador)
+
+	if len(erros) > 0 {
+		return fmt.Errorf("configuração inválida:\n%w", errors.Join(erros...))
+	}
+	return nil
+}
+
+// Validar normaliza listas e nomes (maiúsculas, sem espaços e repetições) e
+// verifica todos os campos, retornando um erro por linha para cada problema.
+func (cfg *Config) Validar() error {
+	var erros []error
+	invalido := func(campo, formato string, args ...any) {
+		erros = append(erros, fmt.Errorf("%s: "+formato, append([]any{campo}, args...)...))
+	}
+
+	if cfg.Servidor.Porta < 1 || cfg.Servidor.Porta > 65535 {
+		invalido("servidor.porta", "%d fora do intervalo 1-65535", cfg.Servidor.Porta)
+	}
+	if cfg.AWS.Regiao == "" {
+		invalido("aws.regiao", "obrigatória")
+	}
+
+	cfg.Armazenamento.Backend = strings.ToLower(strings.TrimSpace(cfg.Armazenamento.Backend))
+	switch cfg.Armazenamento.Backend {
+	case "dynamodb":
+		if cfg.Armazenamento.TabelaDynamo == "" {
+			invalido("armazenamento.tabela_dynamo", "obrigatória com o backend dynamodb")
+		}
+	case "sqlite":
+		if cfg.Armazenamento.CaminhoSQLite == "" {
+			invalido("armazenamento.caminho_sqlite", "obrigatório com o backend sqlite")
+		}
+	case "memory":
+	default:
+		invalido("armazenamento.backend", "%q desconhecido (use %s)", cfg.Armazenamento.Backend, strings.Join(Backends, ", "))
+	}
+
+	cfg.Segredo.Fonte = strings.ToLower(strings.TrimSpace(cfg.Segredo.Fonte))
+	switch cfg.Segredo.Fonte {
+	case "secretsmanager", "ssm":
+		if cfg.Segredo.Nome == "" {
+			invalido("segredo.nome", "obrigatório com a fonte %s", cfg.Segredo.Fonte)
+		}
+	case "env":
+		if cfg.Segredo.Variavel == "" {
+			invalido("segredo.variavel", "obrigatória com a fonte env")
+		}
+	case "file":
+		if cfg.Segredo.Arquivo == "" {
+			invalido("segredo.arquivo", "obrigatório com a fonte file")
+		}
+	default:
+		invalido("segredo.fonte", "%q desconhecida (use %s)", cfg.Segredo.Fonte, strings.Join(FontesSegredo, ", "))
+	}
+	if cfg.Segredo.TTL <= 0 {
+		invalido("segredo.ttl", "%s deve ser maior que zero", cfg.Segredo.TTL)
+	}
+	if cfg.Segredo.Renovacao < 0 {
+		invalido("segredo.renovacao", "%s não pode ser negativa", cfg.Segredo.Renovacao)
+	}
+
+	cfg.Provedores.Ordem = normalizar(cfg.Provedores.Ordem, strings.ToLower)
+	if len(cfg.Provedores.Ordem) == 0 {
+		invalido("provedores.ordem", "informe ao menos um provedor (%s)", strings.Join(NomesProvedores, ", "))
+	}
+	for _, nome := range cfg.Provedores.Ordem {
+		if !slices.Contains(NomesProvedores, nome) {
+			invalido("provedores.ordem", "provedor %q desconhecido (use %s)", nome, strings.Join(NomesProvedores, ", "))
+		}
+	}
+	for _, url := range []struct{ campo, valor string }{
+		{"provedores.fixer_url", cfg.Provedores.FixerURL},
+		{"provedores.bcb_url", cfg.Provedores.BCBURL},
+		{"provedores.exchangerate_url", cfg.Provedores.ExchangeRateURL},
+	} {
+		if !strings.HasPrefix(url.valor, "http://") && !strings.HasPrefix(url.valor, "https://") {
+			invalido(url.campo, "%q não é uma URL http(s)", url.valor)
+		}
+	}
+	cfg.Provedores.FixerURL = strings.TrimRight(cfg.Provedores.FixerURL, "/")
+	cfg.Provedores.BCBURL = strings.TrimRight(cfg.Provedores.BCBURL, "/")
+	cfg.Provedores.ExchangeRateURL = strings.TrimRight(cfg.Provedores.ExchangeRateURL, "/")
+	if cfg.Provedores.Timeout <= 0 {
+		invalido("provedores.timeout", "%s deve ser maior que zero", cfg.Provedores.Timeout)
+	}
+	if cfg.Provedores.Tentativas < 1 || cfg.Provedores.Tentativas > 10 {
+		invalido("provedores.tentativas", "%d fora do intervalo 1-10", cfg.Provedores.Tentativas)
+	}
+	if cfg.Provedores.EsperaInicial <= 0 {
+		invalido("provedores.espera_inicial", "%s deve ser maior que zero", cfg.Provedores.EsperaInicial)
+	}
+	if cfg.Provedores.EsperaMaxima < cfg.Provedores.EsperaInicial {
+		invalido("provedores.espera_maxima", "%s não pode ser menor que provedores.espera_inicial (%s)", cfg.Provedores.EsperaMaxima, cfg.Provedores.EsperaInicial)
+	}
+	if cfg.Provedores.FalhasCircuito < 0 {
+		invalido("provedores.falhas_circuito", "%d não pode ser negativo", cfg.Provedores.FalhasCircuito)
+	}
+	if cfg.Provedores.PausaCircuito <= 0 {
+		invalido("provedores.pausa_circuito", "%s deve ser maior que zero", cfg.Provedores.PausaCircuito)
+	}
+
+	cfg.Moedas.Permitidas = normalizar(cfg.Moedas.Permitidas, strings.ToUpper)
+	if len(cfg.Moedas.Permitidas) < 2 {
+		invalido("moedas.permitidas", "informe ao menos duas moedas")
+	}
+	for _, moeda := range cfg.Moedas.Permitidas {
+		if len(moeda) != 3 {
+			invalido("moedas.permitidas", "%q não é um código ISO 4217 de três letras", moeda)
+		}
+	}
+	cfg.Moedas.Pivo = strings.ToUpper(strings.TrimSpace(cfg.Moedas.Pivo))
+	if !slices.Contains(cfg.Moedas.Permitidas, cfg.Moedas.Pivo) {
+		invalido("moedas.pivo", "%q não está entre as moedas permitidas", cfg.Moedas.Pivo)
+	}
+
+	if cfg.Cotacao.CasasDecimais < 0 || cfg.Cotacao.CasasDecimais > 20 {
+		invalido("cotacao.casas_decimais", "%d fora do intervalo 0-20", cfg.Cotacao.CasasDecimais)
+	}
+	if cfg.Cotacao.Validade < 0 {
+		invalido("cotacao.validade", "%s não pode ser negativa", cfg.Cotacao.Validade)
+	}
+	if cfg.Cotacao.IdadeMaxima < 0 {
+		invalido("cotacao.idade_maxima", "%s não pode ser negativa", cfg.Cotacao.IdadeMaxima)
+	}
+	if cfg.Conversao.CasasDecimais < 0 || cfg.Conversao.CasasDecimais > 20 {
+		invalido("conversao.casas_decimais", "%d fora do intervalo 0-20", cfg.Conversao.CasasDecimais)
+	}
+	cfg.Conversao.Arredondamento = strings.ToLower(strings.TrimSpace(cfg.Conversao.Arredondamento))
+	if !slices.Contains(Arredondamentos, cfg.Conversao.Arredondamento) {
+		invalido("conversao.arredondamento", "%q desconhecido (use %s)", cfg.Conversao.Arredondamento, strings.Join(Arredondamentos, ", "))
+	}
+
+	if cfg.Historico.LayoutData == "" {
+		invalido("historico.layout_data", "obrigatório")
+	} else if t, err := time.Parse(cfg.Historico.LayoutData, time.Date(2025, 4, 20, 12, 30, 0, 0, time.UTC).Format(cfg.Historico.LayoutData)); err != nil || t.Year() != 2025 {
+		invalido("historico.layout_data", "%q não é um layout de data do Go com ano, mês e dia", cfg.Historico.LayoutData)
+	}
+	if cfg.Historico.LimitePadrao < 1 || cfg.Historico.LimitePadrao > cfg.Historico.LimiteMaximo {
+		invalido("historico.limite_padrao", "%d deve estar entre 1 e historico.limite_maximo (%d)", cfg.Historico.LimitePadrao, cfg.Historico.LimiteMaximo)
+	}
+	if _, err := time.LoadLocation(cfg.Historico.Fuso); err != nil || cfg.Historico.Fuso == "" {
+		invalido("historico.fuso", "%q não é um fuso IANA conhecido", cfg.Historico.Fuso)
+	}
+
+	for _, prazo := range []struct {
+		campo string
+		valor Duracao
+	}{
+		{"prazos.armazenamento", cfg.Prazos.Armazenamento},
+		{"prazos.segredo", cfg.Prazos.Segredo},
+		{"prazos.atualizacao", cfg.Prazos.Atualizacao},
+	} {
+		if prazo.valor <= 0 {
+			invalido(prazo.campo, "%s deve ser maior que zero", prazo.valor)
+		}
+	}
+	if cfg.Prontidao.IdadeMaximaIngestao < 0 {
+		invalido("prontidao.idade_maxima_ingestao", "%s não pode ser negativa", cfg.Prontidao.IdadeMaximaIngestao)
+	}
+
+	cfg.Log.Nivel = strings.ToLower(strings.TrimSpace(cfg.Log.Nivel))
+	if !slices.Contains(NiveisLog, cfg.Log.Nivel) {
+		invalido("log.nivel", "%q desconhecido (use %s)", cfg.Log.Nivel, strings.Join(NiveisLog, ", "))
+	}
+	cfg.Rastreamento.Exportador = strings.ToLower(strings.TrimSpace(cfg.Rastreamento.Exportador))
+	if !slices.Contains(Exportadores, cfg.Rastreamento.Exportador) {
+		invalido("rastreamento.exportador", "%q desconhecido (use %s)", cfg.Rastreamento.Exportador, strings.Join(Exportadores, ", "))
+	}
+
+	if len(erros) > 0 {
+		return fmt.Errorf("configuração inválida:\n%w", errors.Join(erros...))
+	}
+	return nil
+}
+
+// normalizar aplica caixa e remove espaços, itens vazios e repetidos.
+func normalizar(itens []string, caixa func(string) string) []string {
+	var resultado []string
+	for _, item := range itens {
+		item = caixa(strings.TrimSpace(item))
+		if item != "" && !slices.Contains(resultado, item) {
+			resultado = append(resultado, item)
+		}
+	}
+	return resultado
+}
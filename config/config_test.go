@@ -0,0 +1,233 @@
+package config_test
+
+import (
+	"cambio-brl-usd/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// arquivo grava conteudo em um arquivo temporário com o nome dado.
+func arquivo(t *testing.T, nome, conteudo string) string {
+	caminho := filepath.Join(t.TempDir(), nome)
+	if err := os.WriteFile(caminho, []byte(conteudo), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return caminho
+}
+
+func TestPadrao_EhValido(t *testing.T) {
+	cfg := config.Padrao()
+	assert.NoError(t, cfg.Validar())
+	assert.Equal(t, ":8080", cfg.Servidor.Endereco())
+}
+
+func TestCarregar_VariaveisDeAmbiente(t *testing.T) {
+	t.Setenv("PORT", "9090")
+	t.Setenv("STORAGE_BACKEND", "SQLite")
+	t.Setenv("SQLITE_PATH", "/tmp/cotacoes.db")
+	t.Setenv("RATE_PROVIDERS", "bcb, exchangeratehost")
+	t.Setenv("BCB_API_URL", "http://bcb.local/")
+	t.Setenv("MOEDAS_PERMITIDAS", "brl,usd,eur")
+	t.Setenv("COTACAO_CASAS_DECIMAIS", "4")
+	t.Setenv("HISTORICO_LIMITE_MAXIMO", "500")
+	t.Setenv("OTEL_TRACES_EXPORTER", "OTLP")
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Servidor.Endereco())
+	assert.Equal(t, "sqlite", cfg.Armazenamento.Backend)
+	assert.Equal(t, "/tmp/cotacoes.db", cfg.Armazenamento.CaminhoSQLite)
+	assert.Equal(t, []string{"bcb", "exchangeratehost"}, cfg.Provedores.Ordem)
+	assert.Equal(t, "http://bcb.local", cfg.Provedores.BCBURL)
+	assert.Equal(t, []string{"BRL", "USD", "EUR"}, cfg.Moedas.Permitidas)
+	assert.Equal(t, 4, cfg.Cotacao.CasasDecimais)
+	assert.Equal(t, 500, cfg.Historico.LimiteMaximo)
+	assert.Equal(t, "otlp", cfg.Rastreamento.Exportador)
+}
+
+func TestCarregar_RateProviderAntigo(t *testing.T) {
+	t.Setenv("RATE_PROVIDER", "exchangeratehost")
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exchangeratehost"}, cfg.Provedores.Ordem)
+}
+
+func TestCarregar_ArquivoYAML(t *testing.T) {
+	t.Setenv("CONFIG_FILE", arquivo(t, "config.yaml", `
+armazenamento:
+  backend: memory
+provedores:
+  ordem: [exchangeratehost]
+conversao:
+  casas_decimais: 4
+  arredondamento: half_up
+`))
+	t.Setenv("CONVERSAO_CASAS_DECIMAIS", "3")
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "memory", cfg.Armazenamento.Backend)
+	assert.Equal(t, []string{"exchangeratehost"}, cfg.Provedores.Ordem)
+	assert.Equal(t, "half_up", cfg.Conversao.Arredondamento)
+	// A variável de ambiente tem precedência sobre o arquivo
+	assert.Equal(t, 3, cfg.Conversao.CasasDecimais)
+	// Campos ausentes no arquivo mantêm o padrão
+	assert.Equal(t, "us-east-1", cfg.AWS.Regiao)
+}
+
+func TestCarregar_ArquivoJSON(t *testing.T) {
+	t.Setenv("CONFIG_FILE", arquivo(t, "config.json", `{"moedas": {"permitidas": ["USD", "EUR"], "pivo": "USD"}}`))
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"USD", "EUR"}, cfg.Moedas.Permitidas)
+	assert.Equal(t, "USD", cfg.Moedas.Pivo)
+}
+
+func TestCarregar_Duracoes(t *testing.T) {
+	t.Setenv("CONFIG_FILE", arquivo(t, "config.json", `{"segredo": {"ttl": "1h"}}`))
+	t.Setenv("FIXER_SECRET_REFRESH", "90s")
+	t.Setenv("COTACAO_VALIDADE", "0s")
+	t.Setenv("PROVIDER_TIMEOUT", "2s")
+	t.Setenv("PROVIDER_MAX_ATTEMPTS", "5")
+	t.Setenv("UPDATE_TIMEOUT", "1m")
+	t.Setenv("READINESS_MAX_INGESTION_AGE", "25h")
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, time.Duration(cfg.Provedores.Timeout))
+	assert.Equal(t, 5, cfg.Provedores.Tentativas)
+	assert.Equal(t, time.Minute, time.Duration(cfg.Prazos.Atualizacao))
+	assert.Equal(t, time.Hour, time.Duration(cfg.Segredo.TTL))
+	assert.Equal(t, 90*time.Second, time.Duration(cfg.Segredo.Renovacao))
+	assert.Zero(t, cfg.Cotacao.Validade)
+	assert.Equal(t, 25*time.Hour, time.Duration(cfg.Prontidao.IdadeMaximaIngestao))
+
+	t.Setenv("FIXER_SECRET_TTL", "dez minutos")
+	_, err = config.Carregar()
+	assert.ErrorContains(t, err, `FIXER_SECRET_TTL: "dez minutos" não é uma duração`)
+}
+
+func TestCarregar_ArquivoInvalido(t *testing.T) {
+	t.Setenv("CONFIG_FILE", arquivo(t, "config.toml", "porta = 1"))
+	_, err := config.Carregar()
+	assert.ErrorContains(t, err, "extensão não suportada")
+
+	t.Setenv("CONFIG_FILE", arquivo(t, "config.yaml", "servidor: [1, 2"))
+	_, err = config.Carregar()
+	assert.ErrorContains(t, err, "config.yaml")
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "nao-existe.yaml"))
+	_, err = config.Carregar()
+	assert.Error(t, err)
+}
+
+func TestCarregar_NumeroInvalidoNoAmbiente(t *testing.T) {
+	t.Setenv("COTACAO_CASAS_DECIMAIS", "oito")
+
+	_, err := config.Carregar()
+	assert.ErrorContains(t, err, `COTACAO_CASAS_DECIMAIS: "oito" não é um número inteiro`)
+}
+
+func TestValidar_ListaTodosOsProblemas(t *testing.T) {
+	cfg := config.Padrao()
+	cfg.Servidor.Porta = 0
+	cfg.Armazenamento.Backend = "postgres"
+	cfg.Provedores.Ordem = []string{"fixer", "inexistente"}
+	cfg.Provedores.FixerURL = "ftp://fixer"
+	cfg.Moedas.Pivo = "XYZ"
+	cfg.Cotacao.CasasDecimais = -1
+	cfg.Conversao.Arredondamento = "aleatorio"
+	cfg.Historico.LimitePadrao = 2000
+	cfg.Historico.Fuso = "Marte/Olympus"
+	cfg.Segredo.TTL = 0
+	cfg.Segredo.Fonte = "vault"
+	cfg.Provedores.Tentativas = 0
+	cfg.Provedores.EsperaMaxima = config.Duracao(time.Millisecond)
+	cfg.Prazos.Armazenamento = 0
+	cfg.Prontidao.IdadeMaximaIngestao = config.Duracao(-time.Hour)
+	cfg.Log.Nivel = "verbose"
+	cfg.Rastreamento.Exportador = "jaeger"
+
+	err := cfg.Validar()
+
+	for _, trecho := range []string{
+		"servidor.porta: 0 fora do intervalo 1-65535",
+		`armazenamento.backend: "postgres" desconhecido`,
+		`provedores.ordem: provedor "inexistente" desconhecido`,
+		`provedores.fixer_url: "ftp://fixer" não é uma URL http(s)`,
+		`moedas.pivo: "XYZ" não está entre as moedas permitidas`,
+		"cotacao.casas_decimais: -1 fora do intervalo 0-20",
+		`conversao.arredondamento: "aleatorio" desconhecido`,
+		"historico.limite_padrao: 2000 deve estar entre 1 e historico.limite_maximo (1000)",
+		`historico.fuso: "Marte/Olympus" não é um fuso IANA conhecido`,
+		"segredo.ttl: 0s deve ser maior que zero",
+		`segredo.fonte: "vault" desconhecida`,
+		"provedores.tentativas: 0 fora do intervalo 1-10",
+		"provedores.espera_maxima: 1ms não pode ser menor que provedores.espera_inicial (200ms)",
+		"prazos.armazenamento: 0s deve ser maior que zero",
+		"prontidao.idade_maxima_ingestao: -1h0m0s não pode ser negativa",
+		`log.nivel: "verbose" desconhecido`,
+		`rastreamento.exportador: "jaeger" desconhecido`,
+	} {
+		assert.ErrorContains(t, err, trecho)
+	}
+}
+
+func TestValidar_OrdemDosProblemas(t *testing.T) {
+	cfg := config.Padrao()
+	cfg.Provedores.FixerURL = "fixer"
+	cfg.Provedores.BCBURL = "bcb"
+	cfg.Provedores.ExchangeRateURL = "exchangerate"
+	cfg.Prazos.Armazenamento = 0
+	cfg.Prazos.Segredo = 0
+	cfg.Prazos.Atualizacao = 0
+
+	err := cfg.Validar()
+
+	// As mensagens seguem a ordem dos campos, igual em toda execução
+	assert.Regexp(t, `(?s)fixer_url.*bcb_url.*exchangerate_url.*prazos\.armazenamento.*prazos\.segredo.*prazos\.atualizacao`, err.Error())
+}
+
+func TestValidar_FonteDeSegredo(t *testing.T) {
+	cfg := config.Padrao()
+	cfg.Segredo.Fonte = "FILE"
+
+	assert.ErrorContains(t, cfg.Validar(), "segredo.arquivo: obrigatório com a fonte file")
+	assert.Equal(t, "file", cfg.Segredo.Fonte)
+
+	cfg.Segredo.Arquivo = "/run/secrets/fixer_api_key"
+	assert.NoError(t, cfg.Validar())
+}
+
+func TestValidar_LayoutDeData(t *testing.T) {
+	cfg := config.Padrao()
+	cfg.Historico.LayoutData = "15:04"
+
+	assert.ErrorContains(t, cfg.Validar(), "historico.layout_data")
+}
+
+func TestCarregar_ArquivoDeExemplo(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join("..", "config.example.yaml"))
+
+	cfg, err := config.Carregar()
+
+	assert.NoError(t, err)
+	assert.Equal(t, config.Padrao().Historico, cfg.Historico)
+	assert.Equal(t, config.Padrao().Provedores, cfg.Provedores)
+	assert.Equal(t, config.Padrao().Segredo, cfg.Segredo)
+	assert.Equal(t, config.Padrao().Prazos, cfg.Prazos)
+	assert.Equal(t, config.Padrao().Prontidao, cfg.Prontidao)
+	assert.Equal(t, config.Padrao().Log, cfg.Log)
+	assert.Equal(t, config.Padrao().Rastreamento, cfg.Rastreamento)
+}
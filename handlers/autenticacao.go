@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exigirToken aceita só requisições com o cabeçalho "Authorization: Bearer
+// <token>". Com token vazio a rota fica desativada e responde 403.
+func exigirToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"erro": "Atualização desativada: token não configurado"})
+			return
+		}
+
+		recebido, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(recebido), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"erro": "Token inválido"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// Conversao converte valor da moeda de para a moeda para com a última cotação
+// gravada ou, se data for informada, com a cotação mais próxima dela.
+func (h *CotacaoHandler) Conversao(c *gin.Context) {
+	valor, err := decimal.NewFromString(c.Query("valor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Valor inválido"})
+		return
+	}
+
+	de, ok := moedaUnica(c, "de", "")
+	if !ok {
+		return
+	}
+	para, ok := moedaUnica(c, "para", "")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.ValidarConversao(de, para); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	var data time.Time
+	if dataStr := c.Query("data"); dataStr != "" {
+		data, err = time.Parse(h.Service.Config().Historico.LayoutData, dataStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"erro": "Data inválida"})
+			return
+		}
+	}
+
+	conversao, err := h.Service.Converter(c.Request.Context(), valor, de, para, data)
+	if err != nil {
+		responderErro(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conversao)
+}
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversao(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)})
+
+	router := setupRouter(t, func(d *dependencias) { d.repo = repo })
+
+	req, _ := http.NewRequest("GET", "/conversao?valor=123.45&de=%20brl&para=usd%20", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+
+	var corpo map[string]any
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &corpo))
+	assert.Equal(t, "22.22", corpo["valor_convertido"])
+	assert.Equal(t, "0.18", corpo["taxa"])
+	assert.Equal(t, "2025-04-20T12:00:00Z", corpo["data_hora"])
+}
+
+func TestConversao_ParametrosInvalidos(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	for _, query := range []string{
+		"valor=abc&de=BRL&para=USD",
+		"de=BRL&para=USD",
+		"valor=10&de=BRL&para=XYZ",
+		"valor=10&de=BRL&para=USD,EUR",
+		"valor=10&para=USD",
+		"valor=10&de=BRL&para=USD&data=ontem",
+	} {
+		req, _ := http.NewRequest("GET", "/conversao?"+query, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, 400, resp.Code, query)
+	}
+}
+
+func TestConversao_MesmaMoeda(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/conversao?valor=10&de=BRL&para=brl", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	var corpo map[string]any
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &corpo))
+	assert.Equal(t, "10", corpo["valor_convertido"])
+	assert.Equal(t, "1", corpo["taxa"])
+}
+
+func TestConversao_SemCotacao(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/conversao?valor=10&de=BRL&para=USD&data=2025-04-20T12:00", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 404, resp.Code)
+}
@@ -1,35 +1,270 @@
 package handlers
 
 import (
-    "net/http"
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/services"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
 	"time"
-    "github.com/gin-gonic/gin"
-    "cambio-brl-usd/services"
+
+	"github.com/gin-gonic/gin"
 )
 
-func UltimaCotacao(c *gin.Context) {
-    cotacao := services.BuscarUltimaCotacao()
-    c.JSON(http.StatusOK, cotacao)
+// CotacaoHandler atende as rotas de cotações e conversão usando Service.
+type CotacaoHandler struct {
+	Service *services.CotacaoService
+}
+
+// NovoCotacaoHandler cria o handler para o serviço svc.
+func NovoCotacaoHandler(svc *services.CotacaoService) *CotacaoHandler {
+	return &CotacaoHandler{Service: svc}
+}
+
+// Registrar adiciona as rotas do handler em r, inclusive as de saúde
+// (/healthz e /readyz).
+func (h *CotacaoHandler) Registrar(r gin.IRoutes) {
+	r.GET(RotaSaude, h.Saude)
+	r.GET("/readyz", h.Prontidao)
+	r.GET("/cotacao/ultima", h.UltimaCotacao)
+	r.POST("/cotacao/atualizar", exigirToken(h.Service.Config().Servidor.TokenAtualizacao), h.AtualizarCotacoes)
+	r.GET("/cotacao/historico", h.HistoricoCotacao)
+	r.GET("/cotacao/historico/agregado", h.HistoricoAgregado)
+	r.GET("/conversao", h.Conversao)
+}
+
+// UltimaCotacao retorna a cotação mais recente já gravada de origem (padrão
+// Config.Moedas.Pivo) para cada moeda em destino (padrão de destinoPadrao,
+// aceita lista separada por vírgula), sem consultar os provedores. Pares sem a
+// moeda pivô são calculados a partir das cotações dela. Quando há um único
+// destino a resposta é um objeto; com vários, uma lista.
+func (h *CotacaoHandler) UltimaCotacao(c *gin.Context) {
+	origem := services.NormalizarMoedas(c.DefaultQuery("origem", h.Service.Config().Moedas.Pivo))
+	if len(origem) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Informe uma única moeda de origem"})
+		return
+	}
+	destinos := services.NormalizarMoedas(c.DefaultQuery("destino", destinoPadrao(origem[0])))
+
+	if err := h.Service.ValidarMoedas(origem[0], destinos); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	cotacoes, err := h.Service.UltimasCotacoes(c.Request.Context(), origem[0], destinos)
+	if err != nil {
+		responderErro(c, err)
+		return
+	}
+	responderCotacoes(c, cotacoes)
+}
+
+// AtualizarCotacoes busca nos provedores e grava a cotação de origem (padrão
+// Config.Moedas.Pivo) para cada moeda em destino (padrão: todas as outras
+// moedas permitidas). Exige o token de Config.Servidor.TokenAtualizacao.
+func (h *CotacaoHandler) AtualizarCotacoes(c *gin.Context) {
+	origem := services.NormalizarMoedas(c.DefaultQuery("origem", h.Service.Config().Moedas.Pivo))
+	if len(origem) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Informe uma única moeda de origem"})
+		return
+	}
+
+	destinos := services.NormalizarMoedas(c.Query("destino"))
+	if len(destinos) == 0 {
+		destinos = h.Service.DestinosPadrao(origem[0])
+	}
+
+	if err := h.Service.ValidarMoedas(origem[0], destinos); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	cotacoes, err := h.Service.AtualizarCotacoes(c.Request.Context(), origem[0], destinos)
+	if err != nil {
+		responderErro(c, err)
+		return
+	}
+	responderCotacoes(c, cotacoes)
+}
+
+// destinoPadrao é a moeda de destino das rotas de leitura quando ela não é
+// informada: USD ou, se a origem já for USD, BRL.
+func destinoPadrao(origem string) string {
+	if origem == "USD" {
+		return "BRL"
+	}
+	return "USD"
+}
+
+// moedaUnica lê a moeda do parâmetro de consulta nome (padrao se ausente),
+// normalizada por services.NormalizarMoedas. Se não houver exatamente uma,
+// responde 400 e retorna false.
+func moedaUnica(c *gin.Context, nome, padrao string) (string, bool) {
+	moedas := services.NormalizarMoedas(c.DefaultQuery(nome, padrao))
+	if len(moedas) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Informe uma única moeda no parâmetro " + nome})
+		return "", false
+	}
+	return moedas[0], true
+}
+
+// responderCotacoes envia um objeto quando há uma única cotação e uma lista
+// quando há várias.
+func responderCotacoes(c *gin.Context, cotacoes []models.Cotacao) {
+	if len(cotacoes) == 1 {
+		c.JSON(http.StatusOK, cotacoes[0])
+		return
+	}
+	c.JSON(http.StatusOK, cotacoes)
+}
+
+// HistoricoCotacao retorna as cotações do par origem (padrão
+// Config.Moedas.Pivo) → destino (padrão de destinoPadrao) gravadas entre
+// inicio e fim, em páginas de até limit itens. O next_cursor da resposta é
+// enviado como cursor para obter a página seguinte.
+func (h *CotacaoHandler) HistoricoCotacao(c *gin.Context) {
+	origem, ok := moedaUnica(c, "origem", h.Service.Config().Moedas.Pivo)
+	if !ok {
+		return
+	}
+	destino, ok := moedaUnica(c, "destino", destinoPadrao(origem))
+	if !ok {
+		return
+	}
+
+	if err := h.Service.ValidarMoedas(origem, []string{destino}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
+
+	inicioStr := c.Query("inicio")
+	fimStr := c.Query("fim")
+
+	layout := h.Service.Config().Historico.LayoutData // padrão: "2025-04-18T18:30"
+
+	inicio, err := time.Parse(layout, inicioStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de início inválida"})
+		return
+	}
+
+	fim, err := time.Parse(layout, fimStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de fim inválida"})
+		return
+	}
+
+	limites := h.Service.Config().Historico
+	limite, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(limites.LimitePadrao)))
+	if err != nil || limite <= 0 || limite > limites.LimiteMaximo {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": fmt.Sprintf("limit deve ser um número entre 1 e %d", limites.LimiteMaximo)})
+		return
+	}
+
+	pagina, err := h.Service.BuscarHistoricoPaginado(c.Request.Context(), origem, destino, inicio, fim, limite, c.Query("cursor"))
+	if err != nil {
+		responderErro(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pagina)
 }
 
-func HistoricoCotacao(c *gin.Context) {
-    inicioStr := c.Query("inicio")
-    fimStr := c.Query("fim")
+// HistoricoAgregado retorna candles (abertura, máxima, mínima, fechamento,
+// média e quantidade) do par origem → destino entre inicio e fim, agrupados
+// por intervalo (1h, 1d, 1w ou 1M). Origem e destino têm os padrões de
+// HistoricoCotacao. As datas de entrada e as fronteiras dos intervalos usam o
+// fuso tz (padrão Config.Historico.Fuso).
+func (h *CotacaoHandler) HistoricoAgregado(c *gin.Context) {
+	origem, ok := moedaUnica(c, "origem", h.Service.Config().Moedas.Pivo)
+	if !ok {
+		return
+	}
+	destino, ok := moedaUnica(c, "destino", destinoPadrao(origem))
+	if !ok {
+		return
+	}
 
-    layout := "2006-01-02T15:04" // formato de entrada: "2025-04-18T18:30"
+	if err := h.Service.ValidarMoedas(origem, []string{destino}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
 
-    inicio, err := time.Parse(layout, inicioStr)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de início inválida"})
-        return
-    }
+	intervalo, err := services.ParseIntervalo(c.Query("intervalo"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": err.Error()})
+		return
+	}
 
-    fim, err := time.Parse(layout, fimStr)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de fim inválida"})
-        return
-    }
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", h.Service.Config().Historico.Fuso))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Fuso horário inválido"})
+		return
+	}
 
-    historico := services.BuscarHistorico(inicio, fim)
-    c.JSON(http.StatusOK, historico)
-}
\ No newline at end of file
+	layout := h.Service.Config().Historico.LayoutData
+
+	inicio, err := time.ParseInLocation(layout, c.Query("inicio"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de início inválida"})
+		return
+	}
+
+	fim, err := time.ParseInLocation(layout, c.Query("fim"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"erro": "Data de fim inválida"})
+		return
+	}
+
+	candles, err := h.Service.AgregarHistorico(c.Request.Context(), origem, destino, inicio, fim, intervalo, loc)
+	if err != nil {
+		responderErro(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, candles)
+}
+
+// statusClienteDesistiu é o status (não padronizado, usado pelo nginx)
+// registrado quando o cliente fecha a conexão antes da resposta.
+const statusClienteDesistiu = 499
+
+// responderErro converte os erros do pacote services no status HTTP
+// correspondente. O detalhe do erro fica só no log. Prazos vencidos respondem
+// 504 qualquer que seja a operação que esgotou o tempo, configuração inválida
+// responde 500 e requisições abandonadas pelo cliente, 499.
+func responderErro(c *gin.Context, err error) {
+	var status int
+	var mensagem string
+	switch {
+	case errors.Is(err, services.ErrCursorInvalido):
+		status, mensagem = http.StatusBadRequest, "Cursor inválido"
+	case errors.Is(err, context.DeadlineExceeded):
+		status, mensagem = http.StatusGatewayTimeout, "Tempo limite excedido"
+	case errors.Is(err, services.ErrConfiguracao):
+		status, mensagem = http.StatusInternalServerError, "Erro interno"
+	case errors.Is(err, services.ErrUpstreamIndisponivel):
+		status, mensagem = http.StatusBadGateway, "Nenhum provedor de cotações disponível no momento"
+	case errors.Is(err, services.ErrNaoEncontrado):
+		status, mensagem = http.StatusNotFound, "Cotação não encontrada"
+	case errors.Is(err, services.ErrArmazenamento):
+		status, mensagem = http.StatusServiceUnavailable, "Armazenamento de cotações indisponível"
+	case errors.Is(err, context.Canceled):
+		status = statusClienteDesistiu
+	default:
+		status, mensagem = http.StatusInternalServerError, "Erro interno"
+	}
+
+	nivel := slog.LevelWarn
+	if status >= 500 && status != statusClienteDesistiu {
+		nivel = slog.LevelError
+	}
+	slog.Log(c.Request.Context(), nivel, "erro ao atender requisição", "rota", c.FullPath(), "status", status, "erro", err)
+
+	if mensagem == "" {
+		c.AbortWithStatus(status)
+		return
+	}
+	c.JSON(status, gin.H{"erro": mensagem})
+}
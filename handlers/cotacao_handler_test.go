@@ -1,26 +1,126 @@
 package handlers_test
 
 import (
+	"cambio-brl-usd/config"
 	"cambio-brl-usd/handlers"
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
-func setupRouter() *gin.Engine {
+// ctx é o contexto das chamadas ao repositório nos testes.
+var ctx = context.Background()
+
+// dependencias são a configuração e o armazenamento com que novoHandler monta
+// o serviço.
+type dependencias struct {
+	cfg  config.Config
+	repo repository.CotacaoRepository
+}
+
+// usarProvedor faz a cadeia de provedores consultar apenas um servidor local
+// atendido por h.
+func (d *dependencias) usarProvedor(t *testing.T, h http.HandlerFunc) {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	d.cfg.Provedores.Ordem = []string{"exchangeratehost"}
+	d.cfg.Provedores.ExchangeRateURL = srv.URL
+}
+
+// novoHandler monta o handler com um serviço que grava em memória e consulta
+// só o Fixer, em um endereço que nunca responde, sem esperar entre as
+// tentativas. ajustar, se não for nil, altera essas dependências antes.
+func novoHandler(t *testing.T, ajustar func(*dependencias)) *handlers.CotacaoHandler {
+	d := dependencias{cfg: config.Padrao(), repo: repository.NovoMemoryRepository()}
+	d.cfg.Provedores.Ordem = []string{"fixer"}
+	d.cfg.Provedores.FixerURL = "http://fixer.invalid"
+	d.cfg.Provedores.EsperaInicial = config.Duracao(time.Millisecond)
+	d.cfg.Provedores.EsperaMaxima = config.Duracao(time.Millisecond)
+	if ajustar != nil {
+		ajustar(&d)
+	}
+
+	segredos := services.SecretSourceFunc(func(context.Context) (string, error) { return "token", nil })
+	svc, err := services.NovoCotacaoService(d.cfg, http.DefaultClient, d.repo, segredos, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handlers.NovoCotacaoHandler(svc)
+}
+
+func setupRouter(t *testing.T, ajustar func(*dependencias)) *gin.Engine {
 	r := gin.Default()
-	r.GET("/cotacao/ultima", handlers.UltimaCotacao)
-	r.GET("/cotacao/historico", handlers.HistoricoCotacao)
+	novoHandler(t, ajustar).Registrar(r)
 	return r
 }
 
+// responder responde a toda requisição com status e body.
+func responder(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// provedorLento só responde quando a requisição é cancelada.
+func provedorLento(_ http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+// repositorioComFalha é um armazenamento em memória em que as gravações e as
+// consultas de período devolvem o erro de falhar ou, sem ele, um erro
+// simulado.
+type repositorioComFalha struct {
+	repository.MemoryRepository
+	falhar func(context.Context) error
+}
+
+func (r *repositorioComFalha) erro(ctx context.Context) error {
+	if r.falhar != nil {
+		return r.falhar(ctx)
+	}
+	return errors.New("erro simulado")
+}
+
+func (r *repositorioComFalha) Save(ctx context.Context, _ models.Cotacao) error {
+	return r.erro(ctx)
+}
+
+func (r *repositorioComFalha) Range(ctx context.Context, _, _ string, _, _ time.Time) ([]models.Cotacao, error) {
+	return nil, r.erro(ctx)
+}
+
+func (r *repositorioComFalha) RangePage(ctx context.Context, _, _ string, _, _ time.Time, _ int, _ string) (models.PaginaCotacoes, error) {
+	return models.PaginaCotacoes{}, r.erro(ctx)
+}
+
+// cotacoesSalvas retorna um armazenamento com uma cotação BRL → destino para
+// cada destino.
+func cotacoesSalvas(destinos ...string) *repository.MemoryRepository {
+	repo := repository.NovoMemoryRepository()
+	for _, destino := range destinos {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: destino, Valor: decimal.RequireFromString("0.18"), DataHora: time.Now()})
+	}
+	return repo
+}
+
 func TestUltimaCotacaoHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	handlers.UltimaCotacao(c)
+	c.Request, _ = http.NewRequest("GET", "/cotacao/ultima", nil)
+	h := novoHandler(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
+	h.UltimaCotacao(c)
 
 	// Verifica se o status de resposta foi 200 OK
 	assert.Equal(t, 200, w.Code)
@@ -32,13 +132,13 @@ func TestHistoricoCotacaoHandler(t *testing.T) {
 
 	// Caso de sucesso: datas válidas
 	c.Request, _ = http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-01T00:00&fim=2025-04-30T23:59", nil)
-	handlers.HistoricoCotacao(c)
+	novoHandler(t, nil).HistoricoCotacao(c)
 	assert.Equal(t, 200, w.Code)
 
 }
 
 func TestUltimaCotacao(t *testing.T) {
-	router := setupRouter()
+	router := setupRouter(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
 
 	req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
 	resp := httptest.NewRecorder()
@@ -50,7 +150,7 @@ func TestUltimaCotacao(t *testing.T) {
 }
 
 func TestHistoricoCotacao_ComParametrosValidos(t *testing.T) {
-	router := setupRouter()
+	router := setupRouter(t, nil)
 
 	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-01-01&fim=2025-01-10", nil)
 	resp := httptest.NewRecorder()
@@ -61,7 +161,7 @@ func TestHistoricoCotacao_ComParametrosValidos(t *testing.T) {
 }
 
 func TestHistoricoCotacao_DataInvalida(t *testing.T) {
-	router := setupRouter()
+	router := setupRouter(t, nil)
 
 	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=invalid&fim=2025-01-10", nil)
 	resp := httptest.NewRecorder()
@@ -70,3 +170,422 @@ func TestHistoricoCotacao_DataInvalida(t *testing.T) {
 
 	assert.Equal(t, 400, resp.Code)
 }
+
+func TestUltimaCotacao_VariosDestinos(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD", "EUR") })
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima?origem=brl&destino=USD,EUR", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+
+	var cotacoes []models.Cotacao
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &cotacoes))
+	assert.Len(t, cotacoes, 2)
+	assert.Equal(t, "EUR", cotacoes[1].MoedaDestino)
+}
+
+func TestUltimaCotacao_ParSemAMoedaPivo(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD", "EUR") })
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima?origem=EUR&destino=USD", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"valor":"1"`)
+	assert.Contains(t, resp.Body.String(), `"moeda_pivo":"BRL"`)
+}
+
+func TestUltimaCotacao_OrigemPadraoEhAMoedaPivo(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "USD", MoedaDestino: "BRL", Valor: decimal.RequireFromString("5.5"), DataHora: time.Now()})
+	router := setupRouter(t, func(d *dependencias) {
+		d.repo = repo
+		d.cfg.Moedas.Pivo = "USD"
+	})
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"moeda_origem":"USD"`)
+	assert.Contains(t, resp.Body.String(), `"valor":"5.5"`)
+}
+
+func TestUltimaCotacao_MoedaNaoPermitida(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima?destino=XYZ", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestUltimaCotacao_VariasOrigens(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima?origem=BRL,USD", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestUltimaCotacao_NaoConsultaProvedores(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.usarProvedor(t, responder(http.StatusOK, `{"base":"BRL","rates":{"USD":0.18}}`))
+	})
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	// Nada gravado ainda: a leitura não dispara uma busca
+	assert.Equal(t, 404, resp.Code)
+}
+
+// atualizar envia POST /cotacao/atualizar?query com o token informado.
+func atualizar(router *gin.Engine, query, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/cotacao/atualizar?"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAtualizarCotacoes(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	router := setupRouter(t, func(d *dependencias) {
+		d.repo = repo
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, responder(http.StatusOK, `{"base":"BRL","rates":{"USD":0.18,"EUR":0.16,"GBP":0.13,"ARS":190,"JPY":25}}`))
+	})
+
+	resp := atualizar(router, "", "segredo")
+
+	assert.Equal(t, 200, resp.Code)
+	var cotacoes []models.Cotacao
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &cotacoes))
+	assert.Len(t, cotacoes, 5) // todas as moedas permitidas menos o pivô
+
+	salva, err := repo.Latest(ctx, "BRL", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.16", salva.Valor.String())
+
+	// A leitura passa a encontrar a cotação gravada
+	req, _ := http.NewRequest("GET", "/cotacao/ultima?destino=EUR", nil)
+	leitura := httptest.NewRecorder()
+	router.ServeHTTP(leitura, req)
+	assert.Equal(t, 200, leitura.Code)
+	assert.Contains(t, leitura.Body.String(), `"valor":"0.16"`)
+	assert.Contains(t, leitura.Body.String(), `"provedor":"`)
+	assert.Contains(t, leitura.Body.String(), `"obtida_em":"`)
+}
+
+func TestAtualizarCotacoes_Autenticacao(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, responder(http.StatusOK, `{"base":"BRL","rates":{"USD":0.18}}`))
+	})
+	assert.Equal(t, 401, atualizar(router, "destino=USD", "").Code)
+	assert.Equal(t, 401, atualizar(router, "destino=USD", "errado").Code)
+	assert.Equal(t, 200, atualizar(router, "destino=USD", "segredo").Code)
+
+	// Sem token configurado a rota fica desativada
+	router = setupRouter(t, func(d *dependencias) {
+		d.usarProvedor(t, responder(http.StatusOK, `{"base":"BRL","rates":{"USD":0.18}}`))
+	})
+	assert.Equal(t, 403, atualizar(router, "destino=USD", "").Code)
+}
+
+func TestAtualizarCotacoes_MoedaNaoPermitida(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.cfg.Servidor.TokenAtualizacao = "segredo" })
+
+	assert.Equal(t, 400, atualizar(router, "destino=XYZ", "segredo").Code)
+	assert.Equal(t, 400, atualizar(router, "origem=BRL,USD", "segredo").Code)
+}
+
+func TestAtualizarCotacoes_BadGatewaySemProvedor(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, responder(http.StatusInternalServerError, ""))
+	})
+
+	assert.Equal(t, 502, atualizar(router, "destino=USD", "segredo").Code)
+}
+
+func TestAtualizarCotacoes_ErroAoSalvar(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.repo = &repositorioComFalha{}
+		d.usarProvedor(t, responder(http.StatusOK, `{"base":"BRL","rates":{"USD":0.18}}`))
+	})
+
+	assert.Equal(t, 503, atualizar(router, "destino=USD", "segredo").Code)
+}
+
+func TestAtualizarCotacoes_TimeoutDaRequisicao(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, provedorLento)
+	})
+	prazo, cancelar := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelar()
+
+	req, _ := http.NewRequestWithContext(prazo, "POST", "/cotacao/atualizar?destino=USD", nil)
+	req.Header.Set("Authorization", "Bearer segredo")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 504, resp.Code)
+}
+
+func TestAtualizarCotacoes_ClienteDesistiu(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, provedorLento)
+	})
+	cancelado, cancelar := context.WithCancel(ctx)
+	cancelar()
+
+	req, _ := http.NewRequestWithContext(cancelado, "POST", "/cotacao/atualizar?destino=USD", nil)
+	req.Header.Set("Authorization", "Bearer segredo")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 499, resp.Code)
+}
+
+func TestConversao_ErroDeConfiguracao(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: time.Now()})
+
+	router := setupRouter(t, func(d *dependencias) {
+		d.repo = repo
+		d.cfg.Conversao.Arredondamento = "aleatorio"
+	})
+
+	req, _ := http.NewRequest("GET", "/conversao?valor=1&de=BRL&para=USD", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 500, resp.Code)
+}
+
+func TestHistoricoCotacao_ErroNoArmazenamento(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = &repositorioComFalha{} })
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-01T00:00&fim=2025-04-30T23:59", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 503, resp.Code)
+}
+
+func TestHistoricoCotacao_TimeoutNoArmazenamento(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.repo = &repositorioComFalha{falhar: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+		d.cfg.Prazos.Armazenamento = config.Duracao(10 * time.Millisecond)
+	})
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-01T00:00&fim=2025-04-30T23:59", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 504, resp.Code)
+}
+
+func TestHistoricoCotacao_FiltraPeloPar(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	dataHora := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: dataHora})
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "EUR", Valor: decimal.RequireFromString("0.16"), DataHora: dataHora})
+
+	router := setupRouter(t, func(d *dependencias) { d.repo = repo })
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?destino=%20eur%20&inicio=2025-04-20T00:00&fim=2025-04-20T23:59", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+
+	var pagina models.PaginaCotacoes
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &pagina))
+	if assert.Len(t, pagina.Items, 1) {
+		assert.Equal(t, "EUR", pagina.Items[0].MoedaDestino)
+	}
+}
+
+func TestHistoricoCotacao_MoedaNaoPermitida(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?destino=XYZ&inicio=2025-04-20T00:00&fim=2025-04-20T23:59", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestHistoricoCotacao_VariasMoedas(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?destino=USD,EUR&inicio=2025-04-20T00:00&fim=2025-04-20T23:59", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+	assert.JSONEq(t, `{"erro":"Informe uma única moeda no parâmetro destino"}`, resp.Body.String())
+}
+
+func TestHistoricoCotacao_Paginado(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	inicio := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: inicio.Add(time.Duration(i) * time.Minute)})
+	}
+
+	router := setupRouter(t, func(d *dependencias) { d.repo = repo })
+
+	var vistas []models.Cotacao
+	url := "/cotacao/historico?inicio=2025-04-20T00:00&fim=2025-04-20T23:59&limit=2"
+	for paginas := 0; ; paginas++ {
+		req, _ := http.NewRequest("GET", url, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, 200, resp.Code)
+
+		var pagina models.PaginaCotacoes
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &pagina))
+		vistas = append(vistas, pagina.Items...)
+
+		if pagina.NextCursor == "" || paginas > 3 {
+			break
+		}
+		url = "/cotacao/historico?inicio=2025-04-20T00:00&fim=2025-04-20T23:59&limit=2&cursor=" + pagina.NextCursor
+	}
+
+	assert.Len(t, vistas, 3)
+}
+
+func TestHistoricoCotacao_LimitInvalido(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-20T00:00&fim=2025-04-20T23:59&limit=0", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestHistoricoCotacao_LayoutELimiteConfigurados(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) {
+		d.cfg.Historico.LayoutData = "2006-01-02"
+		d.cfg.Historico.LimitePadrao = 5
+		d.cfg.Historico.LimiteMaximo = 10
+	})
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-20&fim=2025-04-21", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, 200, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-20&fim=2025-04-21&limit=11", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestHistoricoCotacao_CursorInvalido(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico?inicio=2025-04-20T00:00&fim=2025-04-20T23:59&cursor=xyz", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 400, resp.Code)
+}
+
+func TestHistoricoAgregado(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	// 02:30 UTC de 21/04 ainda é dia 20 em São Paulo (UTC-3)
+	for _, c := range []struct {
+		valor    string
+		dataHora time.Time
+	}{
+		{"0.17", time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)},
+		{"0.19", time.Date(2025, 4, 21, 2, 30, 0, 0, time.UTC)},
+		{"0.18", time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)},
+	} {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString(c.valor), DataHora: c.dataHora})
+	}
+
+	router := setupRouter(t, func(d *dependencias) { d.repo = repo })
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico/agregado?inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=1d", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+
+	var candles []models.Candle
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &candles))
+	if assert.Len(t, candles, 2) {
+		assert.Equal(t, 2, candles[0].Quantidade)
+		assert.Equal(t, "0.19", candles[0].Fechamento.String())
+		assert.Equal(t, 1, candles[1].Quantidade)
+	}
+}
+
+func TestHistoricoAgregado_ParametrosInvalidos(t *testing.T) {
+	router := setupRouter(t, nil)
+
+	for _, query := range []string{
+		"inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=2d",
+		"inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=1d&tz=Lugar/Nenhum",
+		"inicio=20-04-2025&fim=2025-04-21T23:59&intervalo=1d",
+		"inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=1d&destino=XYZ",
+		"inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=1d&destino=USD,EUR",
+	} {
+		req, _ := http.NewRequest("GET", "/cotacao/historico/agregado?"+query, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, 400, resp.Code, query)
+	}
+}
+
+func TestHistoricoAgregado_ErroNoArmazenamento(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = &repositorioComFalha{} })
+
+	req, _ := http.NewRequest("GET", "/cotacao/historico/agregado?inicio=2025-04-20T00:00&fim=2025-04-21T23:59&intervalo=1h", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 503, resp.Code)
+}
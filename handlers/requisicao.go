@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/rastreamento"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// CabecalhoID é o cabeçalho com o ID de correlação da requisição.
+const CabecalhoID = "X-Request-ID"
+
+// idValido limita o X-Request-ID aceito do cliente, para que ele não injete
+// texto arbitrário nos logs.
+var idValido = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// IDRequisicao adota como ID de correlação o X-Request-ID recebido ou, se
+// ele faltar ou for inválido, um ID novo. O ID volta no cabeçalho da
+// resposta e segue no contexto da requisição até os logs.
+func IDRequisicao() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(CabecalhoID)
+		if !idValido.MatchString(id) {
+			id = logs.NovoID()
+		}
+
+		c.Header(CabecalhoID, id)
+		c.Request = c.Request.WithContext(logs.ComID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// Rastrear abre um span de servidor por requisição, nomeado pela rota, que
+// continua o trace do cabeçalho traceparent recebido. Os spans dos provedores
+// e do banco ficam abaixo dele. /metrics e RotaSaude, chamadas a cada poucos
+// segundos pelo Prometheus e pelo App Runner, não são rastreadas.
+func Rastrear() gin.HandlerFunc {
+	return otelgin.Middleware(rastreamento.NomeServico, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/metrics" && r.URL.Path != RotaSaude
+	}))
+}
+
+// Medir registra a duração, a rota e o status de cada requisição em
+// metricas.RequisicoesHTTP. Requisições sem rota (404) ficam com rota
+// "sem_rota", para que caminhos arbitrários não criem séries novas.
+func Medir() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inicio := time.Now()
+		c.Next()
+
+		rota := c.FullPath()
+		if rota == "" {
+			rota = "sem_rota"
+		}
+		metricas.RequisicoesHTTP.WithLabelValues(rota, c.Request.Method, strconv.Itoa(c.Writer.Status())).Observe(metricas.Desde(inicio))
+	}
+}
+
+// RegistrarAcesso escreve uma linha de log por requisição atendida, com rota,
+// status e duração. Deve vir depois de IDRequisicao para levar o ID. As
+// sondagens bem-sucedidas de RotaSaude ficam em nível debug.
+func RegistrarAcesso() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inicio := time.Now()
+		c.Next()
+
+		nivel := slog.LevelInfo
+		switch {
+		case c.Writer.Status() >= 500:
+			nivel = slog.LevelError
+		case c.FullPath() == RotaSaude:
+			nivel = slog.LevelDebug
+		}
+		slog.Log(c.Request.Context(), nivel, "requisição atendida",
+			"metodo", c.Request.Method,
+			"rota", c.FullPath(),
+			"caminho", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duracao_ms", time.Since(inicio).Milliseconds(),
+		)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"bytes"
+	"cambio-brl-usd/handlers"
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/metricas"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// capturarLogs troca o logger padrão por um que escreve, a partir do nível
+// debug, no buffer retornado, até o fim do teste.
+func capturarLogs(t *testing.T) *bytes.Buffer {
+	var saida bytes.Buffer
+	logger, err := logs.Novo(&saida, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anterior := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(anterior) })
+	return &saida
+}
+
+// gravarSpans instala, até o fim do teste, um TracerProvider global que guarda
+// os spans encerrados no gravador retornado.
+func gravarSpans(t *testing.T) *tracetest.SpanRecorder {
+	gravador := tracetest.NewSpanRecorder()
+	anterior := otel.GetTracerProvider()
+	propagador := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(gravador)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(anterior)
+		otel.SetTextMapPropagator(propagador)
+	})
+	return gravador
+}
+
+// routerComLogs monta o router com os middlewares de cmd/api.
+func routerComLogs(t *testing.T, ajustar func(*dependencias)) *gin.Engine {
+	r := gin.New()
+	r.Use(handlers.Rastrear(), handlers.IDRequisicao(), handlers.RegistrarAcesso(), handlers.Medir())
+	novoHandler(t, ajustar).Registrar(r)
+	r.GET("/metrics", gin.WrapH(metricas.Handler()))
+	return r
+}
+
+func TestIDRequisicao_UsaOCabecalhoRecebido(t *testing.T) {
+	saida := capturarLogs(t)
+	router := routerComLogs(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
+	req.Header.Set("X-Request-ID", "pedido-42")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Equal(t, "pedido-42", resp.Header().Get("X-Request-ID"))
+	assert.Contains(t, saida.String(), `"msg":"requisição atendida"`)
+	assert.Contains(t, saida.String(), `"request_id":"pedido-42"`)
+	assert.Contains(t, saida.String(), `"rota":"/cotacao/ultima"`)
+	assert.Contains(t, saida.String(), `"status":200`)
+}
+
+func TestIDRequisicao_GeraIDQuandoAusenteOuInvalido(t *testing.T) {
+	router := routerComLogs(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
+
+	for _, recebido := range []string{"", "id com espaços\n"} {
+		req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
+		req.Header.Set("X-Request-ID", recebido)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Len(t, resp.Header().Get("X-Request-ID"), 32)
+	}
+}
+
+func TestIDRequisicao_ChegaAoLogDoProvedor(t *testing.T) {
+	saida := capturarLogs(t)
+	router := routerComLogs(t, func(d *dependencias) {
+		d.cfg.Servidor.TokenAtualizacao = "segredo"
+		d.usarProvedor(t, responder(http.StatusInternalServerError, ""))
+	})
+
+	req, _ := http.NewRequest("POST", "/cotacao/atualizar?destino=USD", nil)
+	req.Header.Set("Authorization", "Bearer segredo")
+	req.Header.Set("X-Request-ID", "pedido-43")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 502, resp.Code)
+	// Outros testes podem deixar buscas em segundo plano escrevendo no log;
+	// só as linhas desta requisição interessam
+	var linhas []string
+	for _, linha := range strings.Split(saida.String(), "\n") {
+		if strings.Contains(linha, `"request_id":"pedido-43"`) {
+			linhas = append(linhas, linha)
+		}
+	}
+	desta := strings.Join(linhas, "\n")
+	assert.Contains(t, desta, `"msg":"chamada ao provedor"`)
+	assert.Contains(t, desta, `"msg":"provedor falhou"`)
+	assert.Contains(t, desta, `"level":"ERROR","msg":"erro ao atender requisição"`)
+	assert.Contains(t, desta, `"msg":"requisição atendida"`)
+}
+
+func TestRastrear_ContinuaOTraceRecebido(t *testing.T) {
+	gravador := gravarSpans(t)
+	saida := capturarLogs(t)
+	router := routerComLogs(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
+
+	req, _ := http.NewRequest("GET", "/cotacao/ultima", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var requisicao, leitura sdktrace.ReadOnlySpan
+	for _, span := range gravador.Ended() {
+		switch span.Name() {
+		case "/cotacao/ultima":
+			requisicao = span
+		case "armazenamento Latest":
+			leitura = span
+		case "/metrics":
+			t.Error("/metrics não deve ser rastreada")
+		}
+	}
+	if assert.NotNil(t, requisicao) && assert.NotNil(t, leitura) {
+		assert.Equal(t, trace.SpanKindServer, requisicao.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", requisicao.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", requisicao.Parent().SpanID().String())
+		assert.Equal(t, requisicao.SpanContext().SpanID(), leitura.Parent().SpanID())
+	}
+	assert.Contains(t, saida.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+}
+
+func TestMedir(t *testing.T) {
+	router := routerComLogs(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD") })
+	for _, caminho := range []string{"/cotacao/ultima", "/cotacao/ultima?destino=XYZ", "/inexistente/123"} {
+		req, _ := http.NewRequest("GET", caminho, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, 200, resp.Code)
+	assert.Contains(t, resp.Body.String(), `cambio_http_requisicao_duracao_seconds_count{metodo="GET",rota="/cotacao/ultima",status="200"}`)
+	assert.Contains(t, resp.Body.String(), `cambio_http_requisicao_duracao_seconds_count{metodo="GET",rota="/cotacao/ultima",status="400"}`)
+	assert.Contains(t, resp.Body.String(), `cambio_http_requisicao_duracao_seconds_count{metodo="GET",rota="sem_rota",status="404"}`)
+	assert.NotContains(t, resp.Body.String(), "inexistente")
+}
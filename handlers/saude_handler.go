@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"cambio-brl-usd/models"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RotaSaude é a rota sondada pelo App Runner para saber se o processo está
+// vivo. Por ser chamada a cada poucos segundos, não é rastreada e seus
+// acessos bem-sucedidos só vão ao log em nível debug.
+const RotaSaude = "/healthz"
+
+// Saude responde 200 enquanto o processo estiver atendendo, sem consultar
+// nenhuma dependência.
+func (h *CotacaoHandler) Saude(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": models.StatusOK})
+}
+
+// Prontidao verifica o banco, a chave do Fixer e a idade da última ingestão
+// e responde com o resultado de cada dependência: 200 se todas estão
+// disponíveis e 503 se alguma não está.
+func (h *CotacaoHandler) Prontidao(c *gin.Context) {
+	prontidao := h.Service.Prontidao(c.Request.Context())
+	if prontidao.Status == models.StatusOK {
+		c.JSON(http.StatusOK, prontidao)
+		return
+	}
+
+	var falhas []any
+	for nome, dep := range prontidao.Dependencias {
+		if dep.Status != models.StatusOK {
+			falhas = append(falhas, slog.String(nome, dep.Erro))
+		}
+	}
+	slog.WarnContext(c.Request.Context(), "dependências indisponíveis", slog.Group("dependencias", falhas...))
+	c.JSON(http.StatusServiceUnavailable, prontidao)
+}
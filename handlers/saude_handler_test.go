@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/services"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaude(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = &repositorioComFalha{} })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code, "não depende do armazenamento")
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestSaude_SemRastroENoLogDebug(t *testing.T) {
+	gravador := gravarSpans(t)
+	logs := capturarLogs(t)
+	router := routerComLogs(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, gravador.Ended())
+	assert.Contains(t, logs.String(), `"level":"DEBUG"`)
+	assert.Contains(t, logs.String(), `"rota":"/healthz"`)
+}
+
+func TestProntidao(t *testing.T) {
+	router := setupRouter(t, func(d *dependencias) { d.repo = cotacoesSalvas("USD", "EUR") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var prontidao models.Prontidao
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &prontidao))
+	assert.Equal(t, models.StatusOK, prontidao.Status)
+	assert.Equal(t, models.StatusOK, prontidao.Dependencias[services.DependenciaSegredo].Status)
+	assert.Equal(t, models.StatusOK, prontidao.Dependencias[services.DependenciaIngestao].Status)
+	assert.NotNil(t, prontidao.Dependencias[services.DependenciaIngestao].UltimaIngestao)
+}
+
+func TestProntidao_DependenciaIndisponivel(t *testing.T) {
+	logs := capturarLogs(t)
+	router := routerComLogs(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var prontidao models.Prontidao
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &prontidao))
+	assert.Equal(t, models.StatusFalha, prontidao.Status)
+	assert.Equal(t, models.StatusOK, prontidao.Dependencias[services.DependenciaSegredo].Status)
+	assert.Equal(t, models.StatusFalha, prontidao.Dependencias[services.DependenciaIngestao].Status)
+	assert.Equal(t, "nenhuma cotação de BRL gravada", prontidao.Dependencias[services.DependenciaIngestao].Erro)
+	assert.Contains(t, logs.String(), `"msg":"dependências indisponíveis"`)
+	assert.Contains(t, logs.String(), `"ingestao":"nenhuma cotação de BRL gravada"`)
+}
@@ -0,0 +1,97 @@
+// Package logs configura os logs estruturados da aplicação, em JSON no
+// slog, e carrega pelo contexto o ID de correlação de cada requisição, para
+// que todas as linhas escritas ao atendê-la (provedores, DynamoDB, erros)
+// possam ser agrupadas. Linhas escritas dentro de um span do OpenTelemetry
+// levam também o trace_id e o span_id, para cruzar logs e traces.
+package logs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Campos do JSON com o ID de correlação e com o trace e o span do
+// OpenTelemetry.
+const (
+	CampoID    = "request_id"
+	CampoTrace = "trace_id"
+	CampoSpan  = "span_id"
+)
+
+type chaveID struct{}
+
+// ComID retorna uma cópia de ctx que carrega o ID de correlação id.
+func ComID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, chaveID{}, id)
+}
+
+// ID retorna o ID de correlação de ctx, ou "" se não houver.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(chaveID{}).(string)
+	return id
+}
+
+// NovoID gera um ID de correlação aleatório de 32 dígitos hexadecimais.
+func NovoID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Novo cria um logger que escreve em w, em JSON, as mensagens a partir de
+// nivel ("debug", "info", "warn" ou "error"). Mensagens escritas com um
+// contexto que carrega ID de correlação ganham o campo request_id e, com um
+// span válido, os campos trace_id e span_id.
+func Novo(w io.Writer, nivel string) (*slog.Logger, error) {
+	var n slog.Level
+	if err := n.UnmarshalText([]byte(nivel)); err != nil {
+		return nil, err
+	}
+	return slog.New(handlerComID{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: n})}), nil
+}
+
+// Configurar troca o logger padrão do slog (e do pacote log) pelo logger de
+// Novo, escrevendo na saída padrão.
+func Configurar(nivel string) error {
+	logger, err := Novo(os.Stdout, nivel)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// handlerComID acrescenta o ID de correlação e o span do contexto a cada
+// registro.
+type handlerComID struct{ slog.Handler }
+
+func (h handlerComID) Handle(ctx context.Context, r slog.Record) error {
+	if id := ID(ctx); id != "" {
+		r.AddAttrs(slog.String(CampoID, id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String(CampoTrace, span.TraceID().String()), slog.String(CampoSpan, span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h handlerComID) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handlerComID{h.Handler.WithAttrs(attrs)}
+}
+
+func (h handlerComID) WithGroup(nome string) slog.Handler {
+	return handlerComID{h.Handler.WithGroup(nome)}
+}
+
+// Fatal registra err com a mensagem msg em nível error e encerra o processo
+// com status 1. É o log.Fatal dos comandos em cmd.
+func Fatal(msg string, err error) {
+	slog.Error(msg, "erro", err)
+	os.Exit(1)
+}
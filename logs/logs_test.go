@@ -0,0 +1,70 @@
+package logs_test
+
+import (
+	"bytes"
+	"cambio-brl-usd/logs"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNovo_JSONComIDDaRequisicao(t *testing.T) {
+	var saida bytes.Buffer
+	logger, err := logs.Novo(&saida, "info")
+	assert.NoError(t, err)
+
+	ctx := logs.ComID(context.Background(), "abc123")
+	logger.With("provedor", "fixer").InfoContext(ctx, "chamada ao provedor", "status", 200)
+
+	var linha map[string]any
+	assert.NoError(t, json.Unmarshal(saida.Bytes(), &linha))
+	assert.Equal(t, "INFO", linha["level"])
+	assert.Equal(t, "chamada ao provedor", linha["msg"])
+	assert.Equal(t, "abc123", linha["request_id"])
+	assert.Equal(t, "fixer", linha["provedor"])
+	assert.NotEmpty(t, linha["time"])
+}
+
+func TestNovo_TraceDoSpan(t *testing.T) {
+	var saida bytes.Buffer
+	logger, err := logs.Novo(&saida, "info")
+	assert.NoError(t, err)
+
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), span), "chamada ao provedor")
+
+	var linha map[string]any
+	assert.NoError(t, json.Unmarshal(saida.Bytes(), &linha))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", linha["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", linha["span_id"])
+}
+
+func TestNovo_FiltraPeloNivel(t *testing.T) {
+	var saida bytes.Buffer
+	logger, err := logs.Novo(&saida, "warn")
+	assert.NoError(t, err)
+
+	logger.Info("ignorada")
+	assert.Empty(t, saida.String())
+	logger.Warn("escrita")
+	assert.Contains(t, saida.String(), `"msg":"escrita"`)
+	assert.NotContains(t, saida.String(), "request_id")
+	assert.NotContains(t, saida.String(), "trace_id")
+
+	_, err = logs.Novo(&saida, "verbose")
+	assert.Error(t, err)
+}
+
+func TestNovoID(t *testing.T) {
+	id := logs.NovoID()
+
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, logs.NovoID())
+	assert.Empty(t, logs.ID(context.Background()))
+}
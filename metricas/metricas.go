@@ -0,0 +1,94 @@
+// Package metricas define as métricas Prometheus da API, expostas em
+// /metrics: requisições HTTP, chamadas aos provedores de cotações, uso da
+// contingência, acertos do cache de cotações e operações no armazenamento.
+package metricas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registro reúne as métricas da API e as do runtime do Go e do processo.
+var Registro = prometheus.NewRegistry()
+
+var (
+	// RequisicoesHTTP mede a duração das requisições atendidas; a contagem
+	// por rota e status é a série _count.
+	RequisicoesHTTP = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cambio_http_requisicao_duracao_seconds",
+		Help: "Duração das requisições HTTP atendidas, por rota, método e status.",
+	}, []string{"rota", "metodo", "status"})
+
+	// ChamadasProvedor conta as chamadas aos provedores por resultado,
+	// inclusive as barradas pelo circuito aberto ou pela cota esgotada.
+	ChamadasProvedor = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cambio_provedor_chamadas_total",
+		Help: "Chamadas aos provedores de cotações, por provedor e resultado.",
+	}, []string{"provedor", "resultado"})
+
+	// DuracaoProvedor mede cada tentativa enviada a um provedor.
+	DuracaoProvedor = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cambio_provedor_chamada_duracao_seconds",
+		Help: "Duração das tentativas enviadas aos provedores de cotações, por provedor e resultado.",
+	}, []string{"provedor", "resultado"})
+
+	// Contingencia conta as consultas atendidas por um provedor que não é o
+	// primeiro da lista e, com provedor "nenhum", as que nenhum atendeu.
+	Contingencia = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cambio_contingencia_total",
+		Help: "Consultas de cotações que recorreram a um provedor de contingência, pelo provedor que respondeu.",
+	}, []string{"provedor"})
+
+	// Cache conta as buscas no cache de cotações por resultado ("acerto" ou
+	// "falta"); a taxa de acerto é acerto / (acerto + falta).
+	Cache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cambio_cache_cotacoes_total",
+		Help: "Buscas no cache de cotações obtidas dos provedores, por resultado.",
+	}, []string{"resultado"})
+
+	// Armazenamento mede as operações no banco de cotações.
+	Armazenamento = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cambio_armazenamento_operacao_duracao_seconds",
+		Help:    "Duração das operações no armazenamento de cotações, por backend, operação e resultado.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend", "operacao", "resultado"})
+)
+
+func init() {
+	Registro.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequisicoesHTTP, ChamadasProvedor, DuracaoProvedor, Contingencia, Cache, Armazenamento,
+	)
+}
+
+// Handler serve as métricas de Registro no formato do Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registro, promhttp.HandlerOpts{Registry: Registro})
+}
+
+// Resultado resume err no rótulo resultado: "sucesso", "timeout",
+// "cancelada" ou "erro".
+func Resultado(err error) string {
+	switch {
+	case err == nil:
+		return "sucesso"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "cancelada"
+	default:
+		return "erro"
+	}
+}
+
+// Desde retorna os segundos decorridos desde inicio, para os histogramas.
+func Desde(inicio time.Time) float64 {
+	return time.Since(inicio).Seconds()
+}
@@ -0,0 +1,33 @@
+package metricas_test
+
+import (
+	"cambio-brl-usd/metricas"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultado(t *testing.T) {
+	assert.Equal(t, "sucesso", metricas.Resultado(nil))
+	assert.Equal(t, "timeout", metricas.Resultado(fmt.Errorf("consulta: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "cancelada", metricas.Resultado(context.Canceled))
+	assert.Equal(t, "erro", metricas.Resultado(errors.New("falhou")))
+}
+
+func TestHandler(t *testing.T) {
+	metricas.Cache.WithLabelValues("acerto").Inc()
+	metricas.Armazenamento.WithLabelValues("memory", "Save", "sucesso").Observe(0.002)
+
+	resp := httptest.NewRecorder()
+	metricas.Handler().ServeHTTP(resp, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `cambio_cache_cotacoes_total{resultado="acerto"}`)
+	assert.Contains(t, resp.Body.String(), `cambio_armazenamento_operacao_duracao_seconds_count{backend="memory",operacao="Save",resultado="sucesso"}`)
+	assert.Contains(t, resp.Body.String(), "go_goroutines")
+}
@@ -1,10 +1,78 @@
 package models
 
-import "time"
+import (
+	"time"
 
+	"github.com/shopspring/decimal"
+)
+
+// FonteArmazenamento identifica, em Cotacao.Fonte, uma cotação lida do banco
+// em vez de obtida agora de um provedor.
+const FonteArmazenamento = "armazenamento"
+
+// Cotacao é o valor de uma unidade de MoedaOrigem em MoedaDestino. Valor é
+// decimal exato: sai como string no JSON e é gravado como Number no DynamoDB
+// pelo pacote repository, que também lê os itens antigos gravados como float.
 type Cotacao struct {
-    MoedaOrigem  string    `json:"moeda_origem" dynamodbav:"moeda_origem"`
-    MoedaDestino string    `json:"moeda_destino" dynamodbav:"moeda_destino"`
-    Valor        float64   `json:"valor" dynamodbav:"valor"`
-    DataHora     time.Time `json:"data_hora" dynamodbav:"data_hora"`
+	MoedaOrigem  string          `json:"moeda_origem" dynamodbav:"moeda_origem"`
+	MoedaDestino string          `json:"moeda_destino" dynamodbav:"moeda_destino"`
+	Valor        decimal.Decimal `json:"valor" dynamodbav:"-"`
+	DataHora     time.Time       `json:"data_hora" dynamodbav:"data_hora"`
+
+	// Provedor, DataHoraProvedor, ObtidaEm e Contingencia registram de onde
+	// veio a cotação e são gravados com ela, para auditoria: o provedor que
+	// respondeu, o horário das taxas segundo ele (nil se ele não informar),
+	// quando a API fez a consulta e se a resposta veio de um provedor de
+	// contingência, e não do primeiro da ordem configurada. Cotações gravadas
+	// antes desses campos existirem os têm vazios.
+	Provedor         string     `json:"provedor,omitempty" dynamodbav:"provedor,omitempty"`
+	DataHoraProvedor *time.Time `json:"data_hora_provedor,omitempty" dynamodbav:"data_hora_provedor,omitempty"`
+	ObtidaEm         *time.Time `json:"obtida_em,omitempty" dynamodbav:"obtida_em,omitempty"`
+	Contingencia     bool       `json:"contingencia" dynamodbav:"contingencia"`
+
+	// Fonte, Desatualizada e MoedaPivo descrevem como a resposta foi atendida
+	// e não são gravadas: Fonte é o provedor consultado ou
+	// FonteArmazenamento, Desatualizada indica uma cotação salva há mais tempo
+	// que o aceitável e MoedaPivo, a moeda intermediária de uma cotação
+	// calculada pela taxa cruzada.
+	Fonte         string `json:"fonte,omitempty" dynamodbav:"-"`
+	Desatualizada bool   `json:"desatualizada" dynamodbav:"-"`
+	MoedaPivo     string `json:"moeda_pivo,omitempty" dynamodbav:"-"`
+}
+
+// PaginaCotacoes é uma página do histórico. NextCursor é opaco e fica vazio
+// na última página.
+type PaginaCotacoes struct {
+	Items      []Cotacao `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// Candle resume as cotações de um par em um intervalo (abertura, máxima,
+// mínima, fechamento e média). Inicio e Fim delimitam o intervalo no fuso
+// pedido, com Fim exclusivo.
+type Candle struct {
+	MoedaOrigem  string          `json:"moeda_origem"`
+	MoedaDestino string          `json:"moeda_destino"`
+	Inicio       time.Time       `json:"inicio"`
+	Fim          time.Time       `json:"fim"`
+	Abertura     decimal.Decimal `json:"abertura"`
+	Maxima       decimal.Decimal `json:"maxima"`
+	Minima       decimal.Decimal `json:"minima"`
+	Fechamento   decimal.Decimal `json:"fechamento"`
+	Media        decimal.Decimal `json:"media"`
+	Quantidade   int             `json:"quantidade"`
+}
+
+// Conversao é o resultado de converter Valor de De para Para. Taxa é a
+// cotação aplicada e DataHora o momento da cotação usada; quando não há
+// cotação direta entre as moedas, MoedaPivo indica a moeda intermediária e
+// DataHora é a mais antiga das duas cotações.
+type Conversao struct {
+	Valor           decimal.Decimal `json:"valor"`
+	De              string          `json:"de"`
+	Para            string          `json:"para"`
+	ValorConvertido decimal.Decimal `json:"valor_convertido"`
+	Taxa            decimal.Decimal `json:"taxa"`
+	DataHora        time.Time       `json:"data_hora"`
+	MoedaPivo       string          `json:"moeda_pivo,omitempty"`
 }
@@ -0,0 +1,37 @@
+package models_test
+
+import (
+	"cambio-brl-usd/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCotacao_ValorComoStringNoJSON(t *testing.T) {
+	dados, err := json.Marshal(models.Cotacao{Valor: decimal.RequireFromString("0.18")})
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(dados), `"valor":"0.18"`)
+}
+
+func TestCotacao_LeValorNumericoAntigo(t *testing.T) {
+	var cotacao models.Cotacao
+	err := json.Unmarshal([]byte(`{"moeda_origem":"BRL","moeda_destino":"USD","valor":5.42}`), &cotacao)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.42", cotacao.Valor.String())
+}
+
+func TestCotacao_MetadadosNoJSON(t *testing.T) {
+	obtidaEm := time.Date(2025, 4, 21, 12, 3, 0, 0, time.UTC)
+	dados, err := json.Marshal(models.Cotacao{Provedor: "fixer", ObtidaEm: &obtidaEm, Contingencia: true})
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(dados), `"provedor":"fixer"`)
+	assert.Contains(t, string(dados), `"obtida_em":"2025-04-21T12:03:00Z"`)
+	assert.Contains(t, string(dados), `"contingencia":true`)
+	assert.NotContains(t, string(dados), `data_hora_provedor`)
+}
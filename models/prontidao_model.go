@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Status de Prontidao e de cada Dependencia.
+const (
+	StatusOK    = "ok"
+	StatusFalha = "falha"
+)
+
+// Prontidao é a resposta de /readyz: Status é StatusOK quando todas as
+// dependências verificadas estão disponíveis e StatusFalha quando alguma não
+// está. Dependencias traz o resultado de cada uma, pelo nome.
+type Prontidao struct {
+	Status       string                 `json:"status"`
+	Dependencias map[string]Dependencia `json:"dependencias"`
+}
+
+// Dependencia é o resultado da verificação de uma dependência, com o erro
+// quando ela falha e a duração da verificação. UltimaIngestao e Idade só são
+// preenchidas na verificação da ingestão: quando uma atualização obteve
+// cotações pela última vez e há quanto tempo.
+type Dependencia struct {
+	Status         string     `json:"status"`
+	Erro           string     `json:"erro,omitempty"`
+	DuracaoMs      int64      `json:"duracao_ms"`
+	UltimaIngestao *time.Time `json:"ultima_ingestao,omitempty"`
+	Idade          string     `json:"idade,omitempty"`
+}
@@ -0,0 +1,146 @@
+// Package rastreamento configura o rastreamento distribuído da aplicação com
+// o OpenTelemetry: o exportador dos spans, escolhido na configuração, e a
+// propagação do contexto de trace do W3C (traceparent), recebida dos
+// clientes e repassada aos provedores de cotações.
+package rastreamento
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// NomeServico é o service.name dos spans, trocado por OTEL_SERVICE_NAME, e o
+// nome do tracer da aplicação.
+const NomeServico = "cambio-brl-usd"
+
+// Provedor envolve o TracerProvider criado por Novo. Com o exportador "none"
+// não há TracerProvider e os métodos não fazem nada.
+type Provedor struct {
+	tp *sdktrace.TracerProvider
+}
+
+// Novo cria o TracerProvider do exportador escolhido: "none" não exporta,
+// "stdout" escreve cada span em JSON em saida, assim que termina, e "otlp"
+// envia os spans em lotes por OTLP/HTTP para o coletor configurado nas
+// variáveis OTEL_EXPORTER_OTLP_* (por padrão, localhost:4318). A amostragem
+// segue OTEL_TRACES_SAMPLER e, sem ela, respeita a decisão do trace recebido.
+func Novo(ctx context.Context, exportador string, saida io.Writer) (*Provedor, error) {
+	var processador sdktrace.SpanProcessor
+	switch exportador {
+	case "", "none":
+		return &Provedor{}, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(saida))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao criar o exportador stdout: %w", err)
+		}
+		processador = sdktrace.NewSimpleSpanProcessor(exp)
+	case "otlp":
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao criar o exportador OTLP: %w", err)
+		}
+		processador = sdktrace.NewBatchSpanProcessor(exp)
+	default:
+		return nil, fmt.Errorf("exportador de traces desconhecido: %q", exportador)
+	}
+
+	// Os atributos das variáveis OTEL_RESOURCE_ATTRIBUTES e OTEL_SERVICE_NAME
+	// vêm depois e prevalecem sobre o nome padrão.
+	recurso, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(NomeServico)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao descrever o serviço para o rastreamento: %w", err)
+	}
+
+	return &Provedor{tp: sdktrace.NewTracerProvider(
+		sdktrace.WithResource(recurso),
+		sdktrace.WithSpanProcessor(processador),
+	)}, nil
+}
+
+// Configurar cria o provedor de Novo, com o exportador stdout escrevendo na
+// saída padrão, e o instala como TracerProvider global. A propagação W3C
+// (traceparent e baggage) é instalada mesmo com "none", para que o trace de
+// quem chamou a API chegue aos provedores.
+func Configurar(ctx context.Context, exportador string) (*Provedor, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("erro no rastreamento", "erro", err)
+	}))
+
+	p, err := Novo(ctx, exportador, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if p.tp != nil {
+		otel.SetTracerProvider(p.tp)
+	}
+	return p, nil
+}
+
+// TracerProvider retorna o TracerProvider do provedor, ou um que não grava
+// nada com o exportador "none".
+func (p *Provedor) TracerProvider() trace.TracerProvider {
+	if p.tp == nil {
+		return noop.NewTracerProvider()
+	}
+	return p.tp
+}
+
+// Descarregar exporta os spans ainda pendentes. A Lambda chama Descarregar ao
+// fim de cada invocação, antes de ser congelada.
+func (p *Provedor) Descarregar(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
+
+// Encerrar exporta os spans pendentes e libera o exportador.
+func (p *Provedor) Encerrar(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// Iniciar abre o span nome, filho do span de ctx, com o tracer da aplicação
+// no TracerProvider global.
+func Iniciar(ctx context.Context, nome string, opcoes ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(NomeServico).Start(ctx, nome, opcoes...)
+}
+
+// Finalizar marca o span com err, se houver, e o encerra.
+func Finalizar(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ClienteHTTP cria o cliente HTTP dos provedores de cotações: cada
+// requisição vira um span de cliente e leva o traceparent do span atual.
+func ClienteHTTP() *http.Client {
+	return &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+}
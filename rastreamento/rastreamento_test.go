@@ -0,0 +1,142 @@
+package rastreamento_test
+
+import (
+	"bytes"
+	"cambio-brl-usd/rastreamento"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var ctx = context.Background()
+
+// gravarSpans instala, até o fim do teste, um TracerProvider global que guarda
+// os spans encerrados no gravador retornado.
+func gravarSpans(t *testing.T) *tracetest.SpanRecorder {
+	gravador := tracetest.NewSpanRecorder()
+	anterior := otel.GetTracerProvider()
+	propagador := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(gravador)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(anterior)
+		otel.SetTextMapPropagator(propagador)
+	})
+	return gravador
+}
+
+func TestNovo_Stdout(t *testing.T) {
+	var saida bytes.Buffer
+	p, err := rastreamento.Novo(ctx, "stdout", &saida)
+	assert.NoError(t, err)
+
+	_, span := p.TracerProvider().Tracer("teste").Start(ctx, "buscar cotação")
+	span.End()
+	assert.NoError(t, p.Encerrar(ctx))
+
+	assert.Contains(t, saida.String(), `"Name":"buscar cotação"`)
+	assert.Contains(t, saida.String(), `"Value":"cambio-brl-usd"`)
+}
+
+func TestNovo_NomeDoServicoPeloAmbiente(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "cambio-homologacao")
+	var saida bytes.Buffer
+	p, err := rastreamento.Novo(ctx, "stdout", &saida)
+	assert.NoError(t, err)
+
+	_, span := p.TracerProvider().Tracer("teste").Start(ctx, "buscar cotação")
+	span.End()
+	assert.NoError(t, p.Encerrar(ctx))
+
+	assert.Contains(t, saida.String(), `"Value":"cambio-homologacao"`)
+	assert.NotContains(t, saida.String(), `"Value":"cambio-brl-usd"`)
+}
+
+func TestNovo_OTLP(t *testing.T) {
+	var recebidos atomic.Int32
+	coletor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			recebidos.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer coletor.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", coletor.URL)
+
+	p, err := rastreamento.Novo(ctx, "otlp", nil)
+	assert.NoError(t, err)
+
+	_, span := p.TracerProvider().Tracer("teste").Start(ctx, "buscar cotação")
+	span.End()
+	assert.Zero(t, recebidos.Load(), "os spans são enviados em lotes")
+	assert.NoError(t, p.Descarregar(ctx))
+
+	assert.Equal(t, int32(1), recebidos.Load())
+	assert.NoError(t, p.Encerrar(ctx))
+}
+
+func TestNovo_None(t *testing.T) {
+	p, err := rastreamento.Novo(ctx, "none", nil)
+	assert.NoError(t, err)
+
+	_, span := p.TracerProvider().Tracer("teste").Start(ctx, "buscar cotação")
+	assert.False(t, span.IsRecording())
+	assert.NoError(t, p.Descarregar(ctx))
+	assert.NoError(t, p.Encerrar(ctx))
+}
+
+func TestNovo_ExportadorDesconhecido(t *testing.T) {
+	_, err := rastreamento.Novo(ctx, "zipkin", nil)
+
+	assert.ErrorContains(t, err, `exportador de traces desconhecido: "zipkin"`)
+}
+
+func TestFinalizar(t *testing.T) {
+	gravador := gravarSpans(t)
+
+	_, ok := rastreamento.Iniciar(ctx, "ok")
+	rastreamento.Finalizar(ok, nil)
+	_, falha := rastreamento.Iniciar(ctx, "falha")
+	rastreamento.Finalizar(falha, errors.New("tabela indisponível"))
+
+	spans := gravador.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "tabela indisponível", spans[1].Status().Description)
+	assert.Len(t, spans[1].Events(), 1, "o erro fica registrado como evento")
+}
+
+func TestClienteHTTP_PropagaOTrace(t *testing.T) {
+	gravador := gravarSpans(t)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctxSpan, span := rastreamento.Iniciar(ctx, "provedor fixer")
+	req, _ := http.NewRequestWithContext(ctxSpan, "GET", srv.URL, nil)
+	resp, err := rastreamento.ClienteHTTP().Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	traceID := span.SpanContext().TraceID().String()
+	assert.Contains(t, traceparent, traceID)
+	spans := gravador.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID(), "a chamada HTTP é filha do span do provedor")
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/shopspring/decimal"
+)
+
+// TabelaPadrao é o nome da tabela criada pelo Terraform, com chave de
+// partição par ("BRL#USD") e chave de ordenação data_hora.
+const TabelaPadrao = "CotacoesPorPar"
+
+// TabelaLegada é a tabela original, cuja única chave é data_hora. Ela só é
+// lida por MigrarTabelaLegada.
+const TabelaLegada = "Cotacoes"
+
+// DynamoAPI é o subconjunto do cliente do DynamoDB usado pelo repositório,
+// permitindo testá-lo com um cliente falso.
+type DynamoAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// DynamoRepository grava as cotações em uma tabela do DynamoDB particionada
+// por par de moedas e ordenada por data_hora, de modo que Latest e Range são
+// atendidos por Query sem ler a tabela inteira.
+type DynamoRepository struct {
+	client DynamoAPI
+	tabela string
+}
+
+func NovoDynamoRepository(client DynamoAPI, tabela string) *DynamoRepository {
+	return &DynamoRepository{client: client, tabela: tabela}
+}
+
+// Save grava a cotação com gravar e, se o item já existia, atualiza nele só
+// obtida_em.
+func (r *DynamoRepository) Save(ctx context.Context, cotacao models.Cotacao) error {
+	nova, err := r.gravar(ctx, cotacao)
+	if err != nil || nova || cotacao.ObtidaEm == nil {
+		return err
+	}
+	return r.atualizarObtidaEm(ctx, cotacao)
+}
+
+// gravar faz o PutItem da cotação e informa se ela era nova. A condição faz o
+// PutItem falhar, sem sobrescrever, quando já existe um item com o mesmo par
+// e data_hora.
+func (r *DynamoRepository) gravar(ctx context.Context, cotacao models.Cotacao) (bool, error) {
+	item, err := paraItem(cotacao)
+	if err != nil {
+		return false, err
+	}
+
+	enviadaEm := time.Now()
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.tabela),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(par)"),
+	})
+	var existente *types.ConditionalCheckFailedException
+	if errors.As(err, &existente) {
+		slog.DebugContext(ctx, "cotação já gravada no DynamoDB", "tabela", r.tabela, "par", chavePar(cotacao.MoedaOrigem, cotacao.MoedaDestino))
+		return false, nil
+	}
+	r.registrar(ctx, "PutItem", enviadaEm, err)
+	if err != nil {
+		return false, fmt.Errorf("erro ao salvar no DynamoDB: %w", err)
+	}
+	return true, nil
+}
+
+// atualizarObtidaEm troca obtida_em do item já gravado com a chave de
+// cotacao. Se o item tiver sido removido nesse meio-tempo, não faz nada.
+func (r *DynamoRepository) atualizarObtidaEm(ctx context.Context, cotacao models.Cotacao) error {
+	obtidaEm, err := attributevalue.Marshal(*cotacao.ObtidaEm)
+	if err != nil {
+		return fmt.Errorf("erro ao converter obtida_em para DynamoDB: %w", err)
+	}
+
+	enviadaEm := time.Now()
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.tabela),
+		Key:                       chaveDynamo(cotacao.MoedaOrigem, cotacao.MoedaDestino, cotacao.DataHora),
+		UpdateExpression:          aws.String("SET obtida_em = :obtida_em"),
+		ConditionExpression:       aws.String("attribute_exists(par)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":obtida_em": obtidaEm},
+	})
+	var removido *types.ConditionalCheckFailedException
+	if errors.As(err, &removido) {
+		return nil
+	}
+	r.registrar(ctx, "UpdateItem", enviadaEm, err)
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar no DynamoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *DynamoRepository) Latest(ctx context.Context, origem, destino string) (models.Cotacao, error) {
+	condicao := expression.Key("par").Equal(expression.Value(chavePar(origem, destino)))
+
+	cotacao, err := r.primeiraDaConsulta(ctx, condicao, false)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+	if cotacao == nil {
+		return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+	}
+	return *cotacao, nil
+}
+
+// Closest faz dois Query de um item cada: o último antes de t e o primeiro
+// depois dele.
+func (r *DynamoRepository) Closest(ctx context.Context, origem, destino string, t time.Time) (models.Cotacao, error) {
+	par := expression.Key("par").Equal(expression.Value(chavePar(origem, destino)))
+	dataHora := expression.Value(formatarDataHora(t))
+
+	antes, err := r.primeiraDaConsulta(ctx, par.And(expression.Key("data_hora").LessThanEqual(dataHora)), false)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	depois, err := r.primeiraDaConsulta(ctx, par.And(expression.Key("data_hora").GreaterThan(dataHora)), true)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	if proxima := maisProxima(t, antes, depois); proxima != nil {
+		return *proxima, nil
+	}
+	return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+}
+
+// primeiraDaConsulta retorna o primeiro item do Query com a condição dada, na
+// ordem crescente ou decrescente de data_hora, ou nil se não houver nenhum.
+func (r *DynamoRepository) primeiraDaConsulta(ctx context.Context, condicao expression.KeyConditionBuilder, crescente bool) (*models.Cotacao, error) {
+	expr, err := expression.NewBuilder().WithKeyCondition(condicao).Build()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao construir expressão: %w", err)
+	}
+
+	enviadaEm := time.Now()
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tabela),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ScanIndexForward:          aws.Bool(crescente),
+		Limit:                     aws.Int32(1),
+	})
+	r.registrar(ctx, "Query", enviadaEm, err)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar o DynamoDB: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+
+	cotacao, err := deItem(result.Items[0])
+	if err != nil {
+		return nil, err
+	}
+	return &cotacao, nil
+}
+
+func (r *DynamoRepository) Range(ctx context.Context, origem, destino string, inicio, fim time.Time) ([]models.Cotacao, error) {
+	input, err := r.consultaIntervalo(origem, destino, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cada página do Query tem no máximo 1 MB; segue LastEvaluatedKey até o fim
+	cotacoes := []models.Cotacao{}
+	for {
+		enviadaEm := time.Now()
+		result, err := r.client.Query(ctx, input)
+		r.registrar(ctx, "Query", enviadaEm, err)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao consultar o DynamoDB: %w", err)
+		}
+
+		pagina, err := deItens(result.Items)
+		if err != nil {
+			return nil, err
+		}
+		cotacoes = append(cotacoes, pagina...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return cotacoes, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// RangePage faz um único Query com Limit; o cursor é o LastEvaluatedKey
+// devolvido pelo DynamoDB, codificado para o cliente. Ao continuar, o
+// ExclusiveStartKey é montado só com o par e a data_hora validados do cursor.
+func (r *DynamoRepository) RangePage(ctx context.Context, origem, destino string, inicio, fim time.Time, limite int, cursor string) (models.PaginaCotacoes, error) {
+	input, err := r.consultaIntervalo(origem, destino, inicio, fim)
+	if err != nil {
+		return models.PaginaCotacoes{}, err
+	}
+	input.Limit = aws.Int32(int32(limite))
+
+	if cursor != "" {
+		ultima, err := decodificarCursor(cursor, origem, destino, inicio, fim)
+		if err != nil {
+			return models.PaginaCotacoes{}, err
+		}
+		input.ExclusiveStartKey = chaveDynamo(origem, destino, ultima)
+	}
+
+	enviadaEm := time.Now()
+	result, err := r.client.Query(ctx, input)
+	r.registrar(ctx, "Query", enviadaEm, err)
+	if err != nil {
+		return models.PaginaCotacoes{}, fmt.Errorf("erro ao consultar o DynamoDB: %w", err)
+	}
+
+	items, err := deItens(result.Items)
+	if err != nil {
+		return models.PaginaCotacoes{}, err
+	}
+	pagina := models.PaginaCotacoes{Items: items}
+
+	if len(result.LastEvaluatedKey) > 0 {
+		chave := map[string]string{}
+		if err := attributevalue.UnmarshalMap(result.LastEvaluatedKey, &chave); err != nil {
+			return models.PaginaCotacoes{}, fmt.Errorf("erro ao converter LastEvaluatedKey: %w", err)
+		}
+		pagina.NextCursor = codificarCursor(chave)
+	}
+	return pagina, nil
+}
+
+// consultaIntervalo monta o Query da partição do par com data_hora entre
+// inicio e fim.
+func (r *DynamoRepository) consultaIntervalo(origem, destino string, inicio, fim time.Time) (*dynamodb.QueryInput, error) {
+	condicao := expression.Key("par").Equal(expression.Value(chavePar(origem, destino))).
+		And(expression.Key("data_hora").Between(expression.Value(formatarDataHora(inicio)), expression.Value(formatarDataHora(fim))))
+
+	expr, err := expression.NewBuilder().WithKeyCondition(condicao).Build()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao construir expressão: %w", err)
+	}
+
+	return &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tabela),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	}, nil
+}
+
+func (r *DynamoRepository) Delete(ctx context.Context, cotacao models.Cotacao) error {
+	enviadaEm := time.Now()
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tabela),
+		Key:       chaveDynamo(cotacao.MoedaOrigem, cotacao.MoedaDestino, cotacao.DataHora),
+	})
+	r.registrar(ctx, "DeleteItem", enviadaEm, err)
+	if err != nil {
+		return fmt.Errorf("erro ao remover do DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// Verificar confere que a tabela existe e aceita leituras e gravações:
+// status ACTIVE ou UPDATING.
+func (r *DynamoRepository) Verificar(ctx context.Context) error {
+	enviadaEm := time.Now()
+	result, err := r.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(r.tabela),
+	})
+	r.registrar(ctx, "DescribeTable", enviadaEm, err)
+	if err != nil {
+		return fmt.Errorf("erro ao consultar a tabela %s no DynamoDB: %w", r.tabela, err)
+	}
+
+	switch status := result.Table.TableStatus; status {
+	case types.TableStatusActive, types.TableStatusUpdating:
+		return nil
+	default:
+		return fmt.Errorf("tabela %s no DynamoDB com status %s", r.tabela, status)
+	}
+}
+
+// registrar escreve no log a operação op na tabela, com a duração: em nível
+// debug quando dá certo e warn quando falha.
+func (r *DynamoRepository) registrar(ctx context.Context, op string, enviadaEm time.Time, err error) {
+	atributos := []any{"tabela", r.tabela, "operacao", op, "duracao_ms", time.Since(enviadaEm).Milliseconds()}
+	if err != nil {
+		slog.WarnContext(ctx, "falha no DynamoDB", append(atributos, "erro", err)...)
+		return
+	}
+	slog.DebugContext(ctx, "operação no DynamoDB", atributos...)
+}
+
+// chaveDynamo monta a chave primária do item. data_hora é gravada em UTC com
+// largura fixa para que a ordenação da chave seja cronológica.
+func chaveDynamo(origem, destino string, dataHora time.Time) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"par":       &types.AttributeValueMemberS{Value: chavePar(origem, destino)},
+		"data_hora": &types.AttributeValueMemberS{Value: formatarDataHora(dataHora)},
+	}
+}
+
+// paraItem converte a cotação em item do DynamoDB, com valor gravado como
+// Number a partir do decimal exato.
+func paraItem(cotacao models.Cotacao) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(cotacao)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao converter cotação para DynamoDB: %w", err)
+	}
+
+	item["valor"] = &types.AttributeValueMemberN{Value: cotacao.Valor.String()}
+	for nome, valor := range chaveDynamo(cotacao.MoedaOrigem, cotacao.MoedaDestino, cotacao.DataHora) {
+		item[nome] = valor
+	}
+	return item, nil
+}
+
+// deItem faz o caminho inverso de paraItem. Itens antigos, gravados a partir de
+// float64, também têm valor Number e são lidos pelo texto gravado; valor
+// String é aceito para itens escritos à mão.
+func deItem(item map[string]types.AttributeValue) (models.Cotacao, error) {
+	var cotacao models.Cotacao
+	if err := attributevalue.UnmarshalMap(item, &cotacao); err != nil {
+		return models.Cotacao{}, fmt.Errorf("erro ao converter resultados: %w", err)
+	}
+
+	var texto string
+	switch valor := item["valor"].(type) {
+	case *types.AttributeValueMemberN:
+		texto = valor.Value
+	case *types.AttributeValueMemberS:
+		texto = valor.Value
+	default:
+		return models.Cotacao{}, fmt.Errorf("erro ao converter resultados: valor ausente ou de tipo inesperado")
+	}
+
+	valor, err := decimal.NewFromString(texto)
+	if err != nil {
+		return models.Cotacao{}, fmt.Errorf("erro ao converter resultados: valor %q: %w", texto, err)
+	}
+	cotacao.Valor = valor
+	return cotacao, nil
+}
+
+func deItens(items []map[string]types.AttributeValue) ([]models.Cotacao, error) {
+	cotacoes := make([]models.Cotacao, 0, len(items))
+	for _, item := range items {
+		cotacao, err := deItem(item)
+		if err != nil {
+			return nil, err
+		}
+		cotacoes = append(cotacoes, cotacao)
+	}
+	return cotacoes, nil
+}
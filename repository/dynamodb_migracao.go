@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// MigrarTabelaLegada copia todos os itens de tabelaLegada (chave só
+// data_hora) para destino, que grava com a chave par + data_hora. Itens que já
+// existem no destino com a mesma chave não são alterados, então a migração
+// pode ser repetida sem duplicar nem sobrescrever cotações. Retorna quantas
+// cotações foram copiadas e quantas foram ignoradas por já existirem.
+func MigrarTabelaLegada(ctx context.Context, client DynamoAPI, tabelaLegada string, destino *DynamoRepository) (copiadas, ignoradas int, err error) {
+	input := &dynamodb.ScanInput{TableName: aws.String(tabelaLegada)}
+
+	for {
+		result, err := client.Scan(ctx, input)
+		if err != nil {
+			return copiadas, ignoradas, fmt.Errorf("erro ao fazer scan em %s: %w", tabelaLegada, err)
+		}
+
+		cotacoes, err := deItens(result.Items)
+		if err != nil {
+			return copiadas, ignoradas, fmt.Errorf("erro ao converter itens de %s: %w", tabelaLegada, err)
+		}
+
+		for _, cotacao := range cotacoes {
+			nova, err := destino.gravar(ctx, cotacao)
+			if err != nil {
+				return copiadas, ignoradas, err
+			}
+			if nova {
+				copiadas++
+			} else {
+				ignoradas++
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return copiadas, ignoradas, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,551 @@
+package repository_test
+
+import (
+	"bytes"
+	"cambio-brl-usd/logs"
+	"cambio-brl-usd/repository"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// dynamoFake implementa repository.DynamoAPI com funções definidas por teste.
+type dynamoFake struct {
+	put      func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	query    func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	scan     func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error)
+	update   func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	delete   func(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
+	describe func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+}
+
+func (f *dynamoFake) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return f.put(in)
+}
+
+func (f *dynamoFake) Query(_ context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return f.query(in)
+}
+
+func (f *dynamoFake) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return f.scan(in)
+}
+
+func (f *dynamoFake) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return f.update(in)
+}
+
+func (f *dynamoFake) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return f.delete(in)
+}
+
+func (f *dynamoFake) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return f.describe(in)
+}
+
+func itemCotacao(valor, dataHora string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"par":           &types.AttributeValueMemberS{Value: "BRL#USD"},
+		"moeda_origem":  &types.AttributeValueMemberS{Value: "BRL"},
+		"moeda_destino": &types.AttributeValueMemberS{Value: "USD"},
+		"valor":         &types.AttributeValueMemberN{Value: valor},
+		"data_hora":     &types.AttributeValueMemberS{Value: dataHora},
+	}
+}
+
+func TestDynamoRepository_Save(t *testing.T) {
+	var recebido *dynamodb.PutItemInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		put: func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			recebido = in
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}, "Tabela")
+
+	err := repo.Save(ctx, cotacao("USD", "5.42", "2025-04-21T09:00:00-03:00"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Tabela", *recebido.TableName)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "BRL#USD"}, recebido.Item["par"])
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "5.42"}, recebido.Item["valor"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-21T12:00:00.000000000Z"}, recebido.Item["data_hora"])
+	assert.Equal(t, "attribute_not_exists(par)", *recebido.ConditionExpression)
+}
+
+func TestDynamoRepository_Save_Metadados(t *testing.T) {
+	var gravado map[string]types.AttributeValue
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		put: func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			gravado = in.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{gravado}}, nil
+		},
+	}, "Tabela")
+
+	cotacao := comMetadados(cotacao("USD", "5.42", "2025-04-21T12:00:00Z"))
+	assert.NoError(t, repo.Save(ctx, cotacao))
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "bcb"}, gravado["provedor"])
+	assert.Equal(t, &types.AttributeValueMemberBOOL{Value: true}, gravado["contingencia"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-21T12:01:30Z"}, gravado["obtida_em"])
+	assert.Contains(t, gravado, "data_hora_provedor")
+
+	lida, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	conferirMetadados(t, cotacao, lida)
+}
+
+func TestDynamoRepository_Save_ItemExistente(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		put: func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+	}, "Tabela")
+
+	assert.NoError(t, repo.Save(ctx, cotacao("USD", "5.42", "2025-04-21T12:00:00Z")))
+}
+
+func TestDynamoRepository_Save_ItemExistenteAtualizaObtidaEm(t *testing.T) {
+	var recebido *dynamodb.UpdateItemInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		put: func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		},
+		update: func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+			recebido = in
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}, "Tabela")
+
+	assert.NoError(t, repo.Save(ctx, comMetadados(cotacao("USD", "5.42", "2025-04-21T12:00:00Z"))))
+
+	if assert.NotNil(t, recebido) {
+		assert.Equal(t, "Tabela", *recebido.TableName)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "BRL#USD"}, recebido.Key["par"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-21T12:00:00.000000000Z"}, recebido.Key["data_hora"])
+		assert.Equal(t, "SET obtida_em = :obtida_em", *recebido.UpdateExpression)
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-21T12:01:30Z"}, recebido.ExpressionAttributeValues[":obtida_em"])
+	}
+}
+
+func TestDynamoRepository_LeValoresAntigosENovos(t *testing.T) {
+	legadoFloat := itemCotacao("5.4199999999999999", "2025-04-21T12:00:00Z")
+	comoTexto := itemCotacao("0", "2025-04-21T13:00:00Z")
+	comoTexto["valor"] = &types.AttributeValueMemberS{Value: "5.43"}
+
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{legadoFloat, comoTexto}}, nil
+		},
+	}, "Tabela")
+
+	cotacoes, err := repo.Range(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora, cotacao("USD", "0", "2025-04-22T00:00:00Z").DataHora)
+
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 2) {
+		assert.Equal(t, "5.4199999999999999", cotacoes[0].Valor.String())
+		assert.Equal(t, "5.43", cotacoes[1].Valor.String())
+	}
+}
+
+func TestDynamoRepository_ValorAusente(t *testing.T) {
+	item := itemCotacao("0", "2025-04-21T12:00:00Z")
+	delete(item, "valor")
+
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}, "Tabela")
+
+	_, err := repo.Latest(ctx, "BRL", "USD")
+	assert.ErrorContains(t, err, "erro ao converter resultados")
+}
+
+func TestDynamoRepository_Save_ErroPutItem(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		put: func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("erro simulado")
+		},
+	}, "Tabela")
+
+	assert.ErrorContains(t, repo.Save(ctx, cotacao("USD", "5.00", "2025-04-21T12:00:00Z")), "erro simulado")
+}
+
+func TestDynamoRepository_LogComIDDaRequisicao(t *testing.T) {
+	var saida bytes.Buffer
+	logger, err := logs.Novo(&saida, "info")
+	assert.NoError(t, err)
+	anterior := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(anterior)
+
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("erro simulado")
+		},
+	}, "Tabela")
+	repo.Latest(logs.ComID(ctx, "pedido-42"), "BRL", "USD")
+
+	assert.Contains(t, saida.String(), `"msg":"falha no DynamoDB"`)
+	assert.Contains(t, saida.String(), `"operacao":"Query"`)
+	assert.Contains(t, saida.String(), `"request_id":"pedido-42"`)
+}
+
+func TestDynamoRepository_Latest(t *testing.T) {
+	var recebido *dynamodb.QueryInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			recebido = in
+			return &dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{itemCotacao("5.30", "2025-04-21T12:00:00.000000000Z")},
+			}, nil
+		},
+	}, "Tabela")
+
+	ultima, err := repo.Latest(ctx, "BRL", "USD")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.3", ultima.Valor.String())
+	assert.False(t, *recebido.ScanIndexForward)
+	assert.Equal(t, int32(1), *recebido.Limit)
+	assert.Contains(t, recebido.ExpressionAttributeValues, ":0")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "BRL#USD"}, recebido.ExpressionAttributeValues[":0"])
+}
+
+func TestDynamoRepository_Latest_NaoEncontrada(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}, "Tabela")
+
+	_, err := repo.Latest(ctx, "BRL", "USD")
+	assert.ErrorIs(t, err, repository.ErrNaoEncontrado)
+}
+
+func TestDynamoRepository_Latest_ErroNoQuery(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("erro simulado")
+		},
+	}, "Tabela")
+
+	_, err := repo.Latest(ctx, "BRL", "USD")
+	assert.ErrorContains(t, err, "erro simulado")
+}
+
+func TestDynamoRepository_Closest(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			// Anterior a 12:00 está a 3h; a seguinte, a 1h
+			if *in.ScanIndexForward {
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{itemCotacao("5.30", "2025-04-21T13:00:00.000000000Z")}}, nil
+			}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{itemCotacao("5.10", "2025-04-21T09:00:00.000000000Z")}}, nil
+		},
+	}, "Tabela")
+
+	proxima, err := repo.Closest(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-21T12:00:00Z").DataHora)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.3", proxima.Valor.String())
+}
+
+func TestDynamoRepository_Closest_NaoEncontrada(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}, "Tabela")
+
+	_, err := repo.Closest(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-21T12:00:00Z").DataHora)
+	assert.ErrorIs(t, err, repository.ErrNaoEncontrado)
+}
+
+func TestDynamoRepository_Range_PercorreTodasAsPaginas(t *testing.T) {
+	var consultas []*dynamodb.QueryInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			copia := *in
+			consultas = append(consultas, &copia)
+			if in.ExclusiveStartKey == nil {
+				return &dynamodb.QueryOutput{
+					Items:            []map[string]types.AttributeValue{itemCotacao("5.10", "2025-04-20T12:00:00.000000000Z")},
+					LastEvaluatedKey: map[string]types.AttributeValue{"data_hora": &types.AttributeValueMemberS{Value: "x"}},
+				}, nil
+			}
+			return &dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{itemCotacao("5.20", "2025-04-20T13:00:00.000000000Z")},
+			}, nil
+		},
+	}, "Tabela")
+
+	inicio := cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora
+	fim := cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora
+	cotacoes, err := repo.Range(ctx, "BRL", "USD", inicio, fim)
+
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 2) {
+		assert.Equal(t, "5.1", cotacoes[0].Valor.String())
+		assert.Equal(t, "5.2", cotacoes[1].Valor.String())
+	}
+	assert.Len(t, consultas, 2)
+	assert.Equal(t, "Tabela", *consultas[0].TableName)
+	assert.NotNil(t, consultas[0].KeyConditionExpression)
+	assert.Contains(t, consultas[0].ExpressionAttributeValues, ":1")
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-20T00:00:00.000000000Z"}, consultas[0].ExpressionAttributeValues[":1"])
+}
+
+func TestDynamoRepository_Range_ErroNoQuery(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("erro simulado")
+		},
+	}, "Tabela")
+
+	cotacoes, err := repo.Range(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora, cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora)
+	assert.Error(t, err)
+	assert.Nil(t, cotacoes)
+}
+
+func TestDynamoRepository_Range_ErroUnmarshal(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			item := itemCotacao("5.10", "2025-04-20T12:00:00Z")
+			item["valor"] = &types.AttributeValueMemberS{Value: "não é número"}
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		},
+	}, "Tabela")
+
+	cotacoes, err := repo.Range(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora, cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora)
+	assert.ErrorContains(t, err, "erro ao converter resultados")
+	assert.Nil(t, cotacoes)
+}
+
+func TestDynamoRepository_RangePage(t *testing.T) {
+	var recebidos []*dynamodb.QueryInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			recebidos = append(recebidos, in)
+			if in.ExclusiveStartKey == nil {
+				return &dynamodb.QueryOutput{
+					Items: []map[string]types.AttributeValue{itemCotacao("5.10", "2025-04-20T12:00:00.000000000Z")},
+					LastEvaluatedKey: map[string]types.AttributeValue{
+						"par":       &types.AttributeValueMemberS{Value: "BRL#USD"},
+						"data_hora": &types.AttributeValueMemberS{Value: "2025-04-20T12:00:00.000000000Z"},
+					},
+				}, nil
+			}
+			return &dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{itemCotacao("5.20", "2025-04-20T13:00:00.000000000Z")},
+			}, nil
+		},
+	}, "Tabela")
+
+	inicio := cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora
+	fim := cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora
+
+	pagina, err := repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, "")
+	assert.NoError(t, err)
+	assert.Len(t, pagina.Items, 1)
+	assert.NotEmpty(t, pagina.NextCursor)
+	assert.Equal(t, int32(1), *recebidos[0].Limit)
+
+	pagina, err = repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, pagina.NextCursor)
+	assert.NoError(t, err)
+	if assert.Len(t, pagina.Items, 1) {
+		assert.Equal(t, "5.2", pagina.Items[0].Valor.String())
+	}
+	assert.Empty(t, pagina.NextCursor)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-20T12:00:00.000000000Z"}, recebidos[1].ExclusiveStartKey["data_hora"])
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "BRL#USD"}, recebidos[1].ExclusiveStartKey["par"])
+}
+
+func TestDynamoRepository_RangePage_CursorInvalido(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{}, "Tabela")
+
+	_, err := repo.RangePage(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora, cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora, 1, "bad")
+	assert.ErrorIs(t, err, repository.ErrCursorInvalido)
+}
+
+// cursorForjado codifica chave como os cursores devolvidos por RangePage.
+func cursorForjado(t *testing.T, chave map[string]string) string {
+	dados, err := json.Marshal(chave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(dados)
+}
+
+func TestDynamoRepository_RangePage_CursorComOutrosCampos(t *testing.T) {
+	var recebido *dynamodb.QueryInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		query: func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+			recebido = in
+			return &dynamodb.QueryOutput{}, nil
+		},
+	}, "Tabela")
+	cursor := cursorForjado(t, map[string]string{"par": "BRL#USD", "data_hora": "2025-04-20T12:00:00.000000000Z", "valor": "1"})
+
+	_, err := repo.RangePage(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora, cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora, 1, cursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.AttributeValue{
+		"par":       &types.AttributeValueMemberS{Value: "BRL#USD"},
+		"data_hora": &types.AttributeValueMemberS{Value: "2025-04-20T12:00:00.000000000Z"},
+	}, recebido.ExclusiveStartKey)
+}
+
+func TestDynamoRepository_RangePage_CursorForaDoIntervalo(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{}, "Tabela")
+	inicio := cotacao("USD", "0", "2025-04-20T00:00:00Z").DataHora
+	fim := cotacao("USD", "0", "2025-04-21T00:00:00Z").DataHora
+
+	for _, dataHora := range []string{"2025-04-19T12:00:00.000000000Z", "2025-04-22T12:00:00.000000000Z", "ontem"} {
+		cursor := cursorForjado(t, map[string]string{"par": "BRL#USD", "data_hora": dataHora})
+		_, err := repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, cursor)
+		assert.ErrorIs(t, err, repository.ErrCursorInvalido, dataHora)
+	}
+}
+
+func TestDynamoRepository_Delete(t *testing.T) {
+	var recebido *dynamodb.DeleteItemInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		delete: func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+			recebido = in
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}, "Tabela")
+
+	err := repo.Delete(ctx, cotacao("USD", "5.42", "2025-04-21T12:00:00Z"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.AttributeValue{
+		"par":       &types.AttributeValueMemberS{Value: "BRL#USD"},
+		"data_hora": &types.AttributeValueMemberS{Value: "2025-04-21T12:00:00.000000000Z"},
+	}, recebido.Key)
+}
+
+func TestDynamoRepository_Verificar(t *testing.T) {
+	status := types.TableStatusActive
+	var recebido *dynamodb.DescribeTableInput
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		describe: func(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+			recebido = in
+			return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: status}}, nil
+		},
+	}, "Tabela")
+
+	assert.NoError(t, repo.Verificar(ctx))
+	assert.Equal(t, "Tabela", *recebido.TableName)
+
+	status = types.TableStatusDeleting
+	assert.EqualError(t, repo.Verificar(ctx), "tabela Tabela no DynamoDB com status DELETING")
+}
+
+func TestDynamoRepository_Verificar_TabelaInexistente(t *testing.T) {
+	repo := repository.NovoDynamoRepository(&dynamoFake{
+		describe: func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+			return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}
+		},
+	}, "Tabela")
+
+	err := repo.Verificar(ctx)
+
+	var inexistente *types.ResourceNotFoundException
+	assert.ErrorAs(t, err, &inexistente)
+	assert.ErrorContains(t, err, "erro ao consultar a tabela Tabela no DynamoDB")
+}
+
+func TestMigrarTabelaLegada(t *testing.T) {
+	legado := func(valor, dataHora string) map[string]types.AttributeValue {
+		item := itemCotacao(valor, dataHora)
+		delete(item, "par")
+		return item
+	}
+
+	var gravados []map[string]types.AttributeValue
+	client := &dynamoFake{
+		scan: func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+			assert.Equal(t, repository.TabelaLegada, *in.TableName)
+			if in.ExclusiveStartKey == nil {
+				return &dynamodb.ScanOutput{
+					Items:            []map[string]types.AttributeValue{legado("5.10", "2025-04-20T09:00:00-03:00")},
+					LastEvaluatedKey: map[string]types.AttributeValue{"data_hora": &types.AttributeValueMemberS{Value: "x"}},
+				}, nil
+			}
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{legado("5.20", "2025-04-21T12:00:00Z")}}, nil
+		},
+		put: func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			assert.Equal(t, repository.TabelaPadrao, *in.TableName)
+			gravados = append(gravados, in.Item)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	copiadas, ignoradas, err := repository.MigrarTabelaLegada(ctx, client, repository.TabelaLegada, repository.NovoDynamoRepository(client, repository.TabelaPadrao))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copiadas)
+	assert.Zero(t, ignoradas)
+	if assert.Len(t, gravados, 2) {
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "BRL#USD"}, gravados[0]["par"])
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "2025-04-20T12:00:00.000000000Z"}, gravados[0]["data_hora"])
+	}
+}
+
+func TestMigrarTabelaLegada_ErroAoGravar(t *testing.T) {
+	client := &dynamoFake{
+		scan: func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{itemCotacao("5.10", "2025-04-20T12:00:00Z")}}, nil
+		},
+		put: func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			return nil, errors.New("erro simulado")
+		},
+	}
+
+	copiadas, _, err := repository.MigrarTabelaLegada(ctx, client, "Antiga", repository.NovoDynamoRepository(client, "Nova"))
+
+	assert.ErrorContains(t, err, "erro simulado")
+	assert.Equal(t, 0, copiadas)
+}
+
+func TestMigrarTabelaLegada_ItensJaCopiados(t *testing.T) {
+	var gravacoes, atualizados int
+	client := &dynamoFake{
+		scan: func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{
+				itemCotacao("5.10", "2025-04-20T12:00:00Z"),
+				itemCotacao("5.20", "2025-04-21T12:00:00Z"),
+			}}, nil
+		},
+		put: func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			// A primeira cotação já foi copiada numa execução anterior
+			if gravacoes++; gravacoes == 1 {
+				return nil, &types.ConditionalCheckFailedException{}
+			}
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		update: func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+			atualizados++
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	copiadas, ignoradas, err := repository.MigrarTabelaLegada(ctx, client, "Antiga", repository.NovoDynamoRepository(client, "Nova"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, copiadas)
+	assert.Equal(t, 1, ignoradas)
+	assert.Zero(t, atualizados, "itens já copiados não são alterados")
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepository guarda as cotações em memória. Serve para testes e para
+// rodar a API localmente; os dados se perdem ao encerrar o processo.
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	cotacoes []models.Cotacao
+}
+
+func NovoMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, cotacao models.Cotacao) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.indice(cotacao); i >= 0 {
+		if cotacao.ObtidaEm != nil {
+			r.cotacoes[i].ObtidaEm = cotacao.ObtidaEm
+		}
+		return nil
+	}
+
+	// Mantém a lista ordenada por DataHora para Latest e Range
+	i := sort.Search(len(r.cotacoes), func(i int) bool {
+		return r.cotacoes[i].DataHora.After(cotacao.DataHora)
+	})
+	r.cotacoes = append(r.cotacoes, models.Cotacao{})
+	copy(r.cotacoes[i+1:], r.cotacoes[i:])
+	r.cotacoes[i] = cotacao
+	return nil
+}
+
+func (r *MemoryRepository) Latest(ctx context.Context, origem, destino string) (models.Cotacao, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.cotacoes) - 1; i >= 0; i-- {
+		if r.cotacoes[i].MoedaOrigem == origem && r.cotacoes[i].MoedaDestino == destino {
+			return r.cotacoes[i], nil
+		}
+	}
+	return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+}
+
+func (r *MemoryRepository) Closest(ctx context.Context, origem, destino string, t time.Time) (models.Cotacao, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var antes, depois *models.Cotacao
+	for i := range r.cotacoes {
+		cotacao := &r.cotacoes[i]
+		if cotacao.MoedaOrigem != origem || cotacao.MoedaDestino != destino {
+			continue
+		}
+		if cotacao.DataHora.After(t) {
+			depois = cotacao
+			break
+		}
+		antes = cotacao
+	}
+
+	if proxima := maisProxima(t, antes, depois); proxima != nil {
+		return *proxima, nil
+	}
+	return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+}
+
+func (r *MemoryRepository) Range(ctx context.Context, origem, destino string, inicio, fim time.Time) ([]models.Cotacao, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cotacoes := []models.Cotacao{}
+	for _, cotacao := range r.cotacoes {
+		if cotacao.MoedaOrigem != origem || cotacao.MoedaDestino != destino {
+			continue
+		}
+		if !cotacao.DataHora.Before(inicio) && !cotacao.DataHora.After(fim) {
+			cotacoes = append(cotacoes, cotacao)
+		}
+	}
+	return cotacoes, nil
+}
+
+func (r *MemoryRepository) RangePage(ctx context.Context, origem, destino string, inicio, fim time.Time, limite int, cursor string) (models.PaginaCotacoes, error) {
+	if cursor != "" {
+		ultima, err := decodificarCursor(cursor, origem, destino, inicio, fim)
+		if err != nil {
+			return models.PaginaCotacoes{}, err
+		}
+		// Continua logo depois da última cotação entregue
+		inicio = ultima.Add(time.Nanosecond)
+	}
+
+	cotacoes, err := r.Range(ctx, origem, destino, inicio, fim)
+	if err != nil {
+		return models.PaginaCotacoes{}, err
+	}
+	return paginar(cotacoes, limite), nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, cotacao models.Cotacao) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.indice(cotacao); i >= 0 {
+		r.cotacoes = append(r.cotacoes[:i], r.cotacoes[i+1:]...)
+	}
+	return nil
+}
+
+// indice retorna a posição da cotação com o mesmo par e DataHora, ou -1.
+func (r *MemoryRepository) indice(cotacao models.Cotacao) int {
+	for i, c := range r.cotacoes {
+		if c.MoedaOrigem == cotacao.MoedaOrigem && c.MoedaDestino == cotacao.MoedaDestino && c.DataHora.Equal(cotacao.DataHora) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNaoEncontrado é retornado por Latest quando não há cotação para o par.
+var ErrNaoEncontrado = errors.New("cotação não encontrada")
+
+// ErrCursorInvalido é retornado por RangePage quando o cursor não foi gerado
+// por uma página anterior da mesma consulta.
+var ErrCursorInvalido = errors.New("cursor inválido")
+
+// CotacaoRepository é o armazenamento de cotações. As implementações
+// disponíveis são DynamoRepository, MemoryRepository e SQLiteRepository.
+// Todos os métodos recebem o contexto da operação: cancelá-lo ou vencer seu
+// prazo interrompe a consulta ao banco.
+type CotacaoRepository interface {
+	// Save grava a cotação se ainda não houver outra com a mesma chave (par e
+	// DataHora, o horário da cotação no provedor). Gravar de novo a mesma
+	// chave não cria outra cotação nem é erro, então reprocessar os mesmos
+	// dados do provedor é seguro; da cotação existente só ObtidaEm é
+	// atualizada, quando informada, para que ela continue indicando a última
+	// vez que um provedor devolveu a cotação, mesmo que as taxas não mudem.
+	Save(ctx context.Context, cotacao models.Cotacao) error
+	// Latest retorna a cotação mais recente de origem para destino.
+	Latest(ctx context.Context, origem, destino string) (models.Cotacao, error)
+	// Closest retorna a cotação de origem para destino com DataHora mais
+	// próxima de t, antes ou depois dela.
+	Closest(ctx context.Context, origem, destino string, t time.Time) (models.Cotacao, error)
+	// Range retorna as cotações de origem para destino com DataHora entre
+	// inicio e fim, inclusive, em ordem cronológica.
+	Range(ctx context.Context, origem, destino string, inicio, fim time.Time) ([]models.Cotacao, error)
+	// RangePage retorna até limite cotações de Range a partir de cursor
+	// (vazio na primeira página). O NextCursor da página continua a consulta.
+	RangePage(ctx context.Context, origem, destino string, inicio, fim time.Time, limite int, cursor string) (models.PaginaCotacoes, error)
+	// Delete remove a cotação com a mesma chave de cotacao.
+	Delete(ctx context.Context, cotacao models.Cotacao) error
+}
+
+// Verificador é implementado pelos repositórios que dependem de um banco
+// externo. Verificar confere, sem ler nem gravar cotações, que o banco está
+// acessível; é usado por /readyz.
+type Verificador interface {
+	Verificar(ctx context.Context) error
+}
+
+// layoutDataHora grava data_hora em UTC com largura fixa, para que a ordem
+// alfabética da coluna seja a ordem cronológica.
+const layoutDataHora = "2006-01-02T15:04:05.000000000Z"
+
+func formatarDataHora(t time.Time) string {
+	return t.UTC().Format(layoutDataHora)
+}
+
+// chavePar identifica o par de moedas na chave de partição ("BRL#USD").
+func chavePar(origem, destino string) string {
+	return origem + "#" + destino
+}
+
+// codificarCursor transforma a chave da última cotação lida em um token opaco
+// para o cliente.
+func codificarCursor(chave map[string]string) string {
+	dados, _ := json.Marshal(chave)
+	return base64.RawURLEncoding.EncodeToString(dados)
+}
+
+// decodificarCursor faz o caminho inverso de codificarCursor, confere que o
+// cursor pertence ao par consultado e tem data_hora entre inicio e fim, e
+// retorna essa data_hora. Outros campos do cursor são ignorados: ele vem do
+// cliente e só o par e a data_hora validados chegam ao banco.
+func decodificarCursor(cursor, origem, destino string, inicio, fim time.Time) (time.Time, error) {
+	dados, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrCursorInvalido, err)
+	}
+
+	var chave map[string]string
+	if err := json.Unmarshal(dados, &chave); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrCursorInvalido, err)
+	}
+	if chave["par"] != chavePar(origem, destino) {
+		return time.Time{}, fmt.Errorf("%w: cursor não pertence a %s para %s", ErrCursorInvalido, origem, destino)
+	}
+	dataHora, err := time.Parse(layoutDataHora, chave["data_hora"])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrCursorInvalido, err)
+	}
+	if dataHora.Before(inicio) || dataHora.After(fim) {
+		return time.Time{}, fmt.Errorf("%w: cursor fora do intervalo consultado", ErrCursorInvalido)
+	}
+	return dataHora, nil
+}
+
+// cursorDaCotacao é o cursor que continua a leitura depois de cotacao, usado
+// pelos repositórios que paginam pela própria chave (memória e SQLite).
+func cursorDaCotacao(cotacao models.Cotacao) string {
+	return codificarCursor(map[string]string{
+		"par":       chavePar(cotacao.MoedaOrigem, cotacao.MoedaDestino),
+		"data_hora": formatarDataHora(cotacao.DataHora),
+	})
+}
+
+// paginar corta cotacoes em uma página de até limite itens, com o cursor da
+// última cotação entregue quando sobram outras.
+func paginar(cotacoes []models.Cotacao, limite int) models.PaginaCotacoes {
+	if len(cotacoes) <= limite {
+		return models.PaginaCotacoes{Items: cotacoes}
+	}
+	items := cotacoes[:limite]
+	return models.PaginaCotacoes{Items: items, NextCursor: cursorDaCotacao(items[len(items)-1])}
+}
+
+// maisProxima escolhe entre a última cotação antes de t e a primeira depois
+// dela (qualquer uma pode ser nil); no empate fica a anterior.
+func maisProxima(t time.Time, antes, depois *models.Cotacao) *models.Cotacao {
+	switch {
+	case antes == nil:
+		return depois
+	case depois == nil:
+		return antes
+	case depois.DataHora.Sub(t) < t.Sub(antes.DataHora):
+		return depois
+	default:
+		return antes
+	}
+}
+
+// primeira retorna o primeiro item de cotacoes, ou nil se estiver vazia.
+func primeira(cotacoes []models.Cotacao) *models.Cotacao {
+	if len(cotacoes) == 0 {
+		return nil
+	}
+	return &cotacoes[0]
+}
@@ -0,0 +1,168 @@
+package repository_test
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// ctx é o contexto das chamadas aos repositórios nos testes.
+var ctx = context.Background()
+
+func cotacao(destino, valor, dataHora string) models.Cotacao {
+	t, err := time.Parse(time.RFC3339Nano, dataHora)
+	if err != nil {
+		panic(err)
+	}
+	return models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: destino, Valor: decimal.RequireFromString(valor), DataHora: t}
+}
+
+// comMetadados preenche os metadados de origem da cotação.
+func comMetadados(c models.Cotacao) models.Cotacao {
+	dataHoraProvedor := c.DataHora
+	obtidaEm := c.DataHora.Add(90 * time.Second)
+	c.Provedor = "bcb"
+	c.DataHoraProvedor = &dataHoraProvedor
+	c.ObtidaEm = &obtidaEm
+	c.Contingencia = true
+	return c
+}
+
+// conferirMetadados verifica que lida tem os mesmos metadados de gravada.
+func conferirMetadados(t *testing.T, gravada, lida models.Cotacao) {
+	assert.Equal(t, gravada.Provedor, lida.Provedor)
+	assert.Equal(t, gravada.Contingencia, lida.Contingencia)
+	if assert.NotNil(t, lida.DataHoraProvedor) && assert.NotNil(t, lida.ObtidaEm) {
+		assert.True(t, gravada.DataHoraProvedor.Equal(*lida.DataHoraProvedor))
+		assert.True(t, gravada.ObtidaEm.Equal(*lida.ObtidaEm))
+	}
+}
+
+// testarRepositorio verifica o contrato de CotacaoRepository; é executado para
+// cada implementação que não depende da AWS.
+func testarRepositorio(t *testing.T, repo repository.CotacaoRepository) {
+	_, err := repo.Latest(ctx, "BRL", "USD")
+	assert.ErrorIs(t, err, repository.ErrNaoEncontrado)
+
+	for _, c := range []models.Cotacao{
+		cotacao("USD", "0.19", "2025-04-20T12:00:00Z"),
+		cotacao("USD", "0.18", "2025-04-21T12:00:00.5Z"),
+		cotacao("EUR", "0.16", "2025-04-21T13:00:00Z"),
+		cotacao("USD", "0.17", "2025-04-21T12:00:00Z"),
+	} {
+		assert.NoError(t, repo.Save(ctx, c))
+	}
+
+	ultima, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.18", ultima.Valor.String())
+
+	// Gravar de novo com a mesma chave não altera a cotação existente, só
+	// ObtidaEm, quando informada
+	assert.NoError(t, repo.Save(ctx, cotacao("USD", "0.185", "2025-04-21T12:00:00.5Z")))
+	ultima, _ = repo.Latest(ctx, "BRL", "USD")
+	assert.Equal(t, "0.18", ultima.Valor.String())
+	assert.Nil(t, ultima.ObtidaEm)
+
+	regravada := cotacao("USD", "0.185", "2025-04-21T12:00:00.5Z")
+	obtidaEm := regravada.DataHora.Add(6 * time.Hour)
+	regravada.ObtidaEm = &obtidaEm
+	assert.NoError(t, repo.Save(ctx, regravada))
+	ultima, _ = repo.Latest(ctx, "BRL", "USD")
+	assert.Equal(t, "0.18", ultima.Valor.String())
+	if assert.NotNil(t, ultima.ObtidaEm) {
+		assert.True(t, obtidaEm.Equal(*ultima.ObtidaEm))
+	}
+
+	proxima, err := repo.Closest(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-21T01:00:00Z").DataHora)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.17", proxima.Valor.String())
+	proxima, err = repo.Closest(ctx, "BRL", "USD", cotacao("USD", "0", "2025-04-20T13:00:00Z").DataHora)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.19", proxima.Valor.String())
+	_, err = repo.Closest(ctx, "BRL", "JPY", ultima.DataHora)
+	assert.ErrorIs(t, err, repository.ErrNaoEncontrado)
+
+	inicio, _ := time.Parse(time.RFC3339, "2025-04-21T00:00:00Z")
+	fim, _ := time.Parse(time.RFC3339, "2025-04-21T23:59:00Z")
+	cotacoes, err := repo.Range(ctx, "BRL", "USD", inicio, fim)
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 2) {
+		assert.Equal(t, "0.17", cotacoes[0].Valor.String())
+		assert.Equal(t, "0.18", cotacoes[1].Valor.String())
+		assert.True(t, cotacoes[1].DataHora.Equal(ultima.DataHora))
+	}
+
+	cotacoes, err = repo.Range(ctx, "BRL", "EUR", inicio, fim)
+	assert.NoError(t, err)
+	assert.Len(t, cotacoes, 1)
+
+	pagina, err := repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, "")
+	assert.NoError(t, err)
+	if assert.Len(t, pagina.Items, 1) && assert.NotEmpty(t, pagina.NextCursor) {
+		assert.Equal(t, "0.17", pagina.Items[0].Valor.String())
+
+		pagina, err = repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, pagina.NextCursor)
+		assert.NoError(t, err)
+		if assert.Len(t, pagina.Items, 1) {
+			assert.Equal(t, "0.18", pagina.Items[0].Valor.String())
+		}
+		assert.Empty(t, pagina.NextCursor)
+	}
+
+	pagina, err = repo.RangePage(ctx, "BRL", "USD", inicio, fim, 10, "")
+	assert.NoError(t, err)
+	assert.Len(t, pagina.Items, 2)
+	assert.Empty(t, pagina.NextCursor)
+
+	// Cursor de outro par ou adulterado
+	_, err = repo.RangePage(ctx, "BRL", "EUR", inicio, fim, 1, cursorUSD(t, repo, inicio, fim))
+	assert.ErrorIs(t, err, repository.ErrCursorInvalido)
+	_, err = repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, "%%%")
+	assert.ErrorIs(t, err, repository.ErrCursorInvalido)
+
+	// Cursor de uma consulta com outro intervalo
+	_, err = repo.RangePage(ctx, "BRL", "USD", inicio.Add(13*time.Hour), fim, 1, cursorUSD(t, repo, inicio, fim))
+	assert.ErrorIs(t, err, repository.ErrCursorInvalido)
+
+	vazio, err := repo.Range(ctx, "BRL", "USD", fim.Add(time.Hour), fim.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.NotNil(t, vazio)
+	assert.Empty(t, vazio)
+
+	assert.NoError(t, repo.Delete(ctx, ultima))
+	ultima, _ = repo.Latest(ctx, "BRL", "USD")
+	assert.Equal(t, "0.17", ultima.Valor.String())
+	assert.Empty(t, ultima.Provedor)
+	assert.Nil(t, ultima.ObtidaEm)
+
+	// Os metadados de origem são gravados com a cotação
+	gravada := comMetadados(cotacao("GBP", "0.13", "2025-04-21T12:00:00Z"))
+	assert.NoError(t, repo.Save(ctx, gravada))
+	lida, err := repo.Latest(ctx, "BRL", "GBP")
+	assert.NoError(t, err)
+	conferirMetadados(t, gravada, lida)
+}
+
+func cursorUSD(t *testing.T, repo repository.CotacaoRepository, inicio, fim time.Time) string {
+	pagina, err := repo.RangePage(ctx, "BRL", "USD", inicio, fim, 1, "")
+	assert.NoError(t, err)
+	return pagina.NextCursor
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testarRepositorio(t, repository.NovoMemoryRepository())
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repo, err := repository.NovoSQLiteRepository(":memory:")
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	testarRepositorio(t, repo)
+}
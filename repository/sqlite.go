@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // driver "sqlite", sem CGO
+)
+
+// valor é TEXT para guardar o decimal exato; com afinidade REAL o SQLite
+// converteria o texto para ponto flutuante.
+const esquemaSQLite = `
+CREATE TABLE IF NOT EXISTS cotacoes (
+	moeda_origem       TEXT NOT NULL,
+	moeda_destino      TEXT NOT NULL,
+	valor              TEXT NOT NULL,
+	data_hora          TEXT NOT NULL,
+	provedor           TEXT NOT NULL DEFAULT '',
+	data_hora_provedor TEXT,
+	obtida_em          TEXT,
+	contingencia       INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (moeda_origem, moeda_destino, data_hora)
+)`
+
+// colunasMetadados são as colunas acrescentadas depois da criação da tabela,
+// com a definição usada para incluí-las em bancos antigos.
+var colunasMetadados = []struct{ nome, definicao string }{
+	{"provedor", "TEXT NOT NULL DEFAULT ''"},
+	{"data_hora_provedor", "TEXT"},
+	{"obtida_em", "TEXT"},
+	{"contingencia", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// colunasSQLite é a lista de colunas lida por lerCotacao, na ordem esperada.
+const colunasSQLite = `moeda_origem, moeda_destino, valor, data_hora, provedor, data_hora_provedor, obtida_em, contingencia`
+
+// SQLiteRepository guarda as cotações em um arquivo SQLite, para rodar a API
+// localmente sem depender da AWS.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NovoSQLiteRepository abre (ou cria) o banco em caminho e garante que a
+// tabela exista. Use ":memory:" para um banco temporário.
+func NovoSQLiteRepository(caminho string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", caminho)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir SQLite: %w", err)
+	}
+
+	// O SQLite serializa as escritas; uma única conexão evita "database is
+	// locked" e mantém o mesmo banco quando o caminho é ":memory:"
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(esquemaSQLite); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("erro ao criar tabela no SQLite: %w", err)
+	}
+	if err := migrarValorParaTexto(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := incluirMetadados(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+// migrarValorParaTexto recria a tabela de bancos criados quando valor era
+// REAL, convertendo os valores antigos para texto.
+func migrarValorParaTexto(db *sql.DB) error {
+	var tipo string
+	err := db.QueryRow(`SELECT type FROM pragma_table_info('cotacoes') WHERE name = 'valor'`).Scan(&tipo)
+	if err != nil {
+		return fmt.Errorf("erro ao ler esquema do SQLite: %w", err)
+	}
+	if tipo != "REAL" {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao migrar tabela no SQLite: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, comando := range []string{
+		`ALTER TABLE cotacoes RENAME TO cotacoes_real`,
+		esquemaSQLite,
+		`INSERT INTO cotacoes (moeda_origem, moeda_destino, valor, data_hora)
+		 SELECT moeda_origem, moeda_destino, CAST(valor AS TEXT), data_hora FROM cotacoes_real`,
+		`DROP TABLE cotacoes_real`,
+	} {
+		if _, err := tx.Exec(comando); err != nil {
+			return fmt.Errorf("erro ao migrar tabela no SQLite: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao migrar tabela no SQLite: %w", err)
+	}
+	return nil
+}
+
+// incluirMetadados acrescenta as colunas de colunasMetadados que faltarem em
+// bancos criados antes delas. As cotações antigas ficam sem metadados.
+func incluirMetadados(db *sql.DB) error {
+	for _, coluna := range colunasMetadados {
+		var existe bool
+		err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('cotacoes') WHERE name = ?`, coluna.nome).Scan(&existe)
+		if err != nil {
+			return fmt.Errorf("erro ao ler esquema do SQLite: %w", err)
+		}
+		if existe {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE cotacoes ADD COLUMN ` + coluna.nome + ` ` + coluna.definicao); err != nil {
+			return fmt.Errorf("erro ao migrar tabela no SQLite: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// Verificar confere que o arquivo do banco continua acessível.
+func (r *SQLiteRepository) Verificar(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("erro ao acessar o SQLite: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Save(ctx context.Context, cotacao models.Cotacao) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO cotacoes (`+colunasSQLite+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (moeda_origem, moeda_destino, data_hora)
+		 DO UPDATE SET obtida_em = COALESCE(excluded.obtida_em, obtida_em)`,
+		cotacao.MoedaOrigem, cotacao.MoedaDestino, cotacao.Valor.String(), formatarDataHora(cotacao.DataHora),
+		cotacao.Provedor, dataHoraOpcional(cotacao.DataHoraProvedor), dataHoraOpcional(cotacao.ObtidaEm), cotacao.Contingencia)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar no SQLite: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Latest(ctx context.Context, origem, destino string) (models.Cotacao, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+colunasSQLite+` FROM cotacoes
+		 WHERE moeda_origem = ? AND moeda_destino = ?
+		 ORDER BY data_hora DESC LIMIT 1`, origem, destino)
+
+	cotacao, err := lerCotacao(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+	}
+	if err != nil {
+		return models.Cotacao{}, fmt.Errorf("erro ao consultar SQLite: %w", err)
+	}
+	return cotacao, nil
+}
+
+func (r *SQLiteRepository) Closest(ctx context.Context, origem, destino string, t time.Time) (models.Cotacao, error) {
+	antes, err := r.consultar(ctx,
+		`SELECT `+colunasSQLite+` FROM cotacoes
+		 WHERE moeda_origem = ? AND moeda_destino = ? AND data_hora <= ?
+		 ORDER BY data_hora DESC LIMIT 1`, origem, destino, formatarDataHora(t))
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	depois, err := r.consultar(ctx,
+		`SELECT `+colunasSQLite+` FROM cotacoes
+		 WHERE moeda_origem = ? AND moeda_destino = ? AND data_hora > ?
+		 ORDER BY data_hora LIMIT 1`, origem, destino, formatarDataHora(t))
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	if proxima := maisProxima(t, primeira(antes), primeira(depois)); proxima != nil {
+		return *proxima, nil
+	}
+	return models.Cotacao{}, fmt.Errorf("%w: %s para %s", ErrNaoEncontrado, origem, destino)
+}
+
+func (r *SQLiteRepository) Range(ctx context.Context, origem, destino string, inicio, fim time.Time) ([]models.Cotacao, error) {
+	return r.consultar(ctx,
+		`SELECT `+colunasSQLite+` FROM cotacoes
+		 WHERE moeda_origem = ? AND moeda_destino = ? AND data_hora BETWEEN ? AND ?
+		 ORDER BY data_hora`, origem, destino, formatarDataHora(inicio), formatarDataHora(fim))
+}
+
+// RangePage lê limite+1 linhas a partir da data_hora do cursor para saber se
+// há uma próxima página.
+func (r *SQLiteRepository) RangePage(ctx context.Context, origem, destino string, inicio, fim time.Time, limite int, cursor string) (models.PaginaCotacoes, error) {
+	depoisDe := ""
+	if cursor != "" {
+		ultima, err := decodificarCursor(cursor, origem, destino, inicio, fim)
+		if err != nil {
+			return models.PaginaCotacoes{}, err
+		}
+		depoisDe = formatarDataHora(ultima)
+	}
+
+	cotacoes, err := r.consultar(ctx,
+		`SELECT `+colunasSQLite+` FROM cotacoes
+		 WHERE moeda_origem = ? AND moeda_destino = ? AND data_hora BETWEEN ? AND ? AND data_hora > ?
+		 ORDER BY data_hora LIMIT ?`,
+		origem, destino, formatarDataHora(inicio), formatarDataHora(fim), depoisDe, limite+1)
+	if err != nil {
+		return models.PaginaCotacoes{}, err
+	}
+	return paginar(cotacoes, limite), nil
+}
+
+func (r *SQLiteRepository) consultar(ctx context.Context, consulta string, args ...any) ([]models.Cotacao, error) {
+	rows, err := r.db.QueryContext(ctx, consulta, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar SQLite: %w", err)
+	}
+	defer rows.Close()
+
+	cotacoes := []models.Cotacao{}
+	for rows.Next() {
+		cotacao, err := lerCotacao(rows)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter resultados: %w", err)
+		}
+		cotacoes = append(cotacoes, cotacao)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao consultar SQLite: %w", err)
+	}
+	return cotacoes, nil
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, cotacao models.Cotacao) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM cotacoes WHERE moeda_origem = ? AND moeda_destino = ? AND data_hora = ?`,
+		cotacao.MoedaOrigem, cotacao.MoedaDestino, formatarDataHora(cotacao.DataHora))
+	if err != nil {
+		return fmt.Errorf("erro ao remover do SQLite: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func lerCotacao(s scanner) (models.Cotacao, error) {
+	var cotacao models.Cotacao
+	var dataHora string
+	var dataHoraProvedor, obtidaEm sql.NullString
+	if err := s.Scan(&cotacao.MoedaOrigem, &cotacao.MoedaDestino, &cotacao.Valor, &dataHora,
+		&cotacao.Provedor, &dataHoraProvedor, &obtidaEm, &cotacao.Contingencia); err != nil {
+		return models.Cotacao{}, err
+	}
+
+	t, err := time.Parse(layoutDataHora, dataHora)
+	if err != nil {
+		return models.Cotacao{}, fmt.Errorf("data_hora inválida %q: %w", dataHora, err)
+	}
+	cotacao.DataHora = t
+
+	if cotacao.DataHoraProvedor, err = lerDataHoraOpcional(dataHoraProvedor); err != nil {
+		return models.Cotacao{}, fmt.Errorf("data_hora_provedor inválida: %w", err)
+	}
+	if cotacao.ObtidaEm, err = lerDataHoraOpcional(obtidaEm); err != nil {
+		return models.Cotacao{}, fmt.Errorf("obtida_em inválida: %w", err)
+	}
+	return cotacao, nil
+}
+
+// dataHoraOpcional grava t no formato de data_hora, ou NULL se t for nil.
+func dataHoraOpcional(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatarDataHora(*t)
+}
+
+// lerDataHoraOpcional faz o caminho inverso de dataHoraOpcional.
+func lerDataHoraOpcional(valor sql.NullString) (*time.Time, error) {
+	if !valor.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(layoutDataHora, valor.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"cambio-brl-usd/repository"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteRepository_PersisteNoArquivo(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "cotacoes.db")
+
+	repo, err := repository.NovoSQLiteRepository(caminho)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Save(ctx, cotacao("USD", "0.18", "2025-04-21T12:00:00Z")))
+	assert.NoError(t, repo.Close())
+
+	repo, err = repository.NovoSQLiteRepository(caminho)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	ultima, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.18", ultima.Valor.String())
+}
+
+func TestSQLiteRepository_Verificar(t *testing.T) {
+	repo, err := repository.NovoSQLiteRepository(filepath.Join(t.TempDir(), "cotacoes.db"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Verificar(ctx))
+	repo.Close()
+	assert.ErrorContains(t, repo.Verificar(ctx), "erro ao acessar o SQLite")
+}
+
+func TestSQLiteRepository_CaminhoInvalido(t *testing.T) {
+	_, err := repository.NovoSQLiteRepository(filepath.Join(t.TempDir(), "nao-existe", "cotacoes.db"))
+	assert.Error(t, err)
+}
+
+func TestSQLiteRepository_MigraValorReal(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "cotacoes.db")
+
+	// Banco criado antes de valor passar a ser TEXT
+	db, err := sql.Open("sqlite", caminho)
+	assert.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE cotacoes (
+		moeda_origem TEXT NOT NULL, moeda_destino TEXT NOT NULL, valor REAL NOT NULL, data_hora TEXT NOT NULL,
+		PRIMARY KEY (moeda_origem, moeda_destino, data_hora))`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO cotacoes VALUES ('BRL', 'USD', 0.18, '2025-04-21T12:00:00.000000000Z')`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	repo, err := repository.NovoSQLiteRepository(caminho)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	ultima, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.18", ultima.Valor.String())
+
+	// Depois da migração o valor é gravado sem passar por ponto flutuante
+	assert.NoError(t, repo.Save(ctx, cotacao("USD", "0.1234567890123456789", "2025-04-22T12:00:00Z")))
+	ultima, err = repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.1234567890123456789", ultima.Valor.String())
+}
+
+func TestSQLiteRepository_IncluiColunasDeMetadados(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "cotacoes.db")
+
+	// Banco criado antes das colunas de metadados
+	db, err := sql.Open("sqlite", caminho)
+	assert.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE cotacoes (
+		moeda_origem TEXT NOT NULL, moeda_destino TEXT NOT NULL, valor TEXT NOT NULL, data_hora TEXT NOT NULL,
+		PRIMARY KEY (moeda_origem, moeda_destino, data_hora))`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO cotacoes VALUES ('BRL', 'USD', '0.18', '2025-04-21T12:00:00.000000000Z')`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	repo, err := repository.NovoSQLiteRepository(caminho)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	antiga, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.18", antiga.Valor.String())
+	assert.Empty(t, antiga.Provedor)
+	assert.Nil(t, antiga.DataHoraProvedor)
+	assert.False(t, antiga.Contingencia)
+
+	nova := comMetadados(cotacao("USD", "0.19", "2025-04-22T12:00:00Z"))
+	assert.NoError(t, repo.Save(ctx, nova))
+	lida, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	conferirMetadados(t, nova, lida)
+}
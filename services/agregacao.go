@@ -0,0 +1,107 @@
+package services
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Intervalo é a largura de cada candle de AgregarHistorico.
+type Intervalo string
+
+const (
+	IntervaloHora   Intervalo = "1h"
+	IntervaloDia    Intervalo = "1d"
+	IntervaloSemana Intervalo = "1w"
+	IntervaloMes    Intervalo = "1M"
+)
+
+// ParseIntervalo converte "1h", "1d", "1w" ou "1M" em Intervalo.
+func ParseIntervalo(valor string) (Intervalo, error) {
+	switch intervalo := Intervalo(valor); intervalo {
+	case IntervaloHora, IntervaloDia, IntervaloSemana, IntervaloMes:
+		return intervalo, nil
+	default:
+		return "", fmt.Errorf("intervalo inválido %q: use 1h, 1d, 1w ou 1M", valor)
+	}
+}
+
+// inicioDoIntervalo retorna o começo do intervalo que contém t, no fuso de t.
+// Semanas começam na segunda-feira.
+func (i Intervalo) inicioDoIntervalo(t time.Time) time.Time {
+	ano, mes, dia := t.Date()
+	switch i {
+	case IntervaloHora:
+		return time.Date(ano, mes, dia, t.Hour(), 0, 0, 0, t.Location())
+	case IntervaloSemana:
+		diasDesdeSegunda := (int(t.Weekday()) + 6) % 7
+		return time.Date(ano, mes, dia-diasDesdeSegunda, 0, 0, 0, 0, t.Location())
+	case IntervaloMes:
+		return time.Date(ano, mes, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(ano, mes, dia, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// proximo retorna o começo do intervalo seguinte ao que começa em inicio.
+// Dias, semanas e meses são somados no calendário, para que as trocas de
+// horário de verão não desloquem as fronteiras.
+func (i Intervalo) proximo(inicio time.Time) time.Time {
+	switch i {
+	case IntervaloHora:
+		return inicio.Add(time.Hour)
+	case IntervaloSemana:
+		return inicio.AddDate(0, 0, 7)
+	case IntervaloMes:
+		return inicio.AddDate(0, 1, 0)
+	default:
+		return inicio.AddDate(0, 0, 1)
+	}
+}
+
+// AgregarHistorico agrupa as cotações de origem para destino gravadas entre
+// inicio e fim em candles de largura intervalo, com as fronteiras calculadas
+// no fuso loc. Intervalos sem cotações não aparecem no resultado; a média é
+// arredondada para Config.Cotacao.CasasDecimais casas.
+func (s *CotacaoService) AgregarHistorico(ctx context.Context, origem, destino string, inicio, fim time.Time, intervalo Intervalo, loc *time.Location) ([]models.Candle, error) {
+	casas := int32(s.cfg.Cotacao.CasasDecimais)
+
+	cotacoes, err := s.BuscarHistorico(ctx, origem, destino, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := []models.Candle{}
+	var soma decimal.Decimal
+	for _, cotacao := range cotacoes {
+		dataHora := cotacao.DataHora.In(loc)
+
+		atual := len(candles) - 1
+		if atual < 0 || !dataHora.Before(candles[atual].Fim) {
+			comeco := intervalo.inicioDoIntervalo(dataHora)
+			candles = append(candles, models.Candle{
+				MoedaOrigem:  origem,
+				MoedaDestino: destino,
+				Inicio:       comeco,
+				Fim:          intervalo.proximo(comeco),
+				Abertura:     cotacao.Valor,
+				Maxima:       cotacao.Valor,
+				Minima:       cotacao.Valor,
+			})
+			atual++
+			soma = decimal.Zero
+		}
+
+		candle := &candles[atual]
+		candle.Maxima = decimal.Max(candle.Maxima, cotacao.Valor)
+		candle.Minima = decimal.Min(candle.Minima, cotacao.Valor)
+		candle.Fechamento = cotacao.Valor
+		candle.Quantidade++
+		soma = soma.Add(cotacao.Valor)
+		candle.Media = soma.DivRound(decimal.NewFromInt(int64(candle.Quantidade)), casas)
+	}
+	return candles, nil
+}
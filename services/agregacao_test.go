@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIntervalo(t *testing.T) {
+	for _, valor := range []string{"1h", "1d", "1w", "1M"} {
+		intervalo, err := services.ParseIntervalo(valor)
+		assert.NoError(t, err)
+		assert.Equal(t, services.Intervalo(valor), intervalo)
+	}
+
+	_, err := services.ParseIntervalo("1m")
+	assert.Error(t, err)
+}
+
+func TestAgregarHistorico(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) { d.repo = repo })
+	saoPaulo, _ := time.LoadLocation(config.Padrao().Historico.Fuso)
+
+	for _, c := range []struct {
+		valor    string
+		dataHora string
+	}{
+		{"0.20", "2025-04-20T13:00:00Z"},
+		{"0.22", "2025-04-20T13:10:00Z"},
+		{"0.18", "2025-04-20T13:40:00Z"},
+		{"0.19", "2025-04-20T13:50:00Z"},
+		{"0.25", "2025-04-20T15:00:00Z"},
+	} {
+		dataHora, _ := time.Parse(time.RFC3339, c.dataHora)
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString(c.valor), DataHora: dataHora})
+	}
+
+	inicio := time.Date(2025, 4, 20, 0, 0, 0, 0, saoPaulo)
+	candles, err := svc.AgregarHistorico(ctx, "BRL", "USD", inicio, inicio.AddDate(0, 0, 1), services.IntervaloHora, saoPaulo)
+
+	assert.NoError(t, err)
+	if assert.Len(t, candles, 2) {
+		assert.True(t, candles[0].Inicio.Equal(time.Date(2025, 4, 20, 10, 0, 0, 0, saoPaulo)))
+		assert.True(t, candles[0].Fim.Equal(time.Date(2025, 4, 20, 11, 0, 0, 0, saoPaulo)))
+		assert.Equal(t, "0.2", candles[0].Abertura.String())
+		assert.Equal(t, "0.22", candles[0].Maxima.String())
+		assert.Equal(t, "0.18", candles[0].Minima.String())
+		assert.Equal(t, "0.19", candles[0].Fechamento.String())
+		assert.Equal(t, "0.1975", candles[0].Media.String())
+		assert.Equal(t, 4, candles[0].Quantidade)
+
+		assert.Equal(t, 1, candles[1].Quantidade)
+		assert.Equal(t, "0.25", candles[1].Media.String())
+	}
+}
+
+func TestAgregarHistorico_SemanaEMes(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) { d.repo = repo })
+
+	// Quarta, 30/04, e quinta, 01/05: mesma semana, meses diferentes
+	for _, dataHora := range []time.Time{
+		time.Date(2025, 4, 30, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: dataHora})
+	}
+
+	inicio := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	fim := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	semanas, err := svc.AgregarHistorico(ctx, "BRL", "USD", inicio, fim, services.IntervaloSemana, time.UTC)
+	assert.NoError(t, err)
+	if assert.Len(t, semanas, 1) {
+		assert.Equal(t, time.Monday, semanas[0].Inicio.Weekday())
+		assert.Equal(t, 2, semanas[0].Quantidade)
+	}
+
+	meses, err := svc.AgregarHistorico(ctx, "BRL", "USD", inicio, fim, services.IntervaloMes, time.UTC)
+	assert.NoError(t, err)
+	if assert.Len(t, meses, 2) {
+		assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), meses[1].Inicio)
+		assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), meses[1].Fim)
+	}
+}
+
+func TestAgregarHistorico_ErroNoArmazenamento(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
+
+	_, err := svc.AgregarHistorico(ctx, "BRL", "USD", time.Now(), time.Now(), services.IntervaloDia, time.UTC)
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/rastreamento"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClientesAWS reúne a configuração e os clientes da AWS usados pela API. São
+// criados uma vez, na inicialização do servidor ou no cold start da Lambda, e
+// compartilhados por todas as requisições: os clientes do SDK são seguros
+// para uso concorrente e reaproveitam as conexões abertas.
+type ClientesAWS struct {
+	Config         aws.Config
+	DynamoDB       *dynamodb.Client
+	SecretsManager *secretsmanager.Client
+	SSM            *ssm.Client
+}
+
+// NovosClientesAWS lê as credenciais padrão da AWS para a região de cfg e
+// cria os clientes, que registram no slog as novas tentativas das chamadas e
+// abrem um span por operação. ctx só é usado durante a leitura das
+// credenciais.
+func NovosClientesAWS(ctx context.Context, cfg config.AWS) (*ClientesAWS, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(cfg.Regiao),
+		awsconfig.WithLogger(loggerAWS{ctx: context.Background()}),
+		awsconfig.WithClientLogMode(aws.LogRetries),
+		awsconfig.WithAPIOptions([]func(*middleware.Stack) error{rastrearAWS}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: erro ao carregar configuração da AWS: %w", ErrConfiguracao, err)
+	}
+
+	return &ClientesAWS{
+		Config:         awsCfg,
+		DynamoDB:       dynamodb.NewFromConfig(awsCfg),
+		SecretsManager: secretsmanager.NewFromConfig(awsCfg),
+		SSM:            ssm.NewFromConfig(awsCfg),
+	}, nil
+}
+
+// loggerAWS encaminha ao slog os logs do SDK da AWS, com o contexto da
+// chamada que os gerou e, portanto, com o ID da requisição.
+type loggerAWS struct{ ctx context.Context }
+
+func (l loggerAWS) Logf(classificacao logging.Classification, formato string, v ...any) {
+	nivel := slog.LevelDebug
+	if classificacao == logging.Warn {
+		nivel = slog.LevelWarn
+	}
+	slog.Log(l.ctx, nivel, fmt.Sprintf(formato, v...), "origem", "aws-sdk")
+}
+
+func (l loggerAWS) WithContext(ctx context.Context) logging.Logger {
+	return loggerAWS{ctx: ctx}
+}
+
+// rastrearAWS acrescenta às chamadas dos clientes da AWS um span de cliente
+// por operação, como "DynamoDB.Query" ou "Secrets Manager.GetSecretValue",
+// que engloba as novas tentativas feitas pelo SDK.
+func rastrearAWS(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Rastreamento",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			servico, operacao := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+			atributos := []attribute.KeyValue{
+				semconv.RPCSystemKey.String("aws-api"),
+				semconv.RPCService(servico),
+				semconv.RPCMethod(operacao),
+				semconv.CloudRegion(awsmiddleware.GetRegion(ctx)),
+			}
+			if servico == dynamodb.ServiceID {
+				atributos = append(atributos, semconv.DBSystemDynamoDB, semconv.DBOperationName(operacao))
+				if tabela := tabelaDynamo(in.Parameters); tabela != "" {
+					atributos = append(atributos, semconv.AWSDynamoDBTableNames(tabela))
+				}
+			}
+
+			ctx, span := rastreamento.Iniciar(ctx, servico+"."+operacao,
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(atributos...))
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+				span.SetAttributes(attribute.String("aws.request_id", id))
+			}
+			rastreamento.Finalizar(span, err)
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// tabelaDynamo retorna a tabela das operações do DynamoDB feitas pelos
+// repositórios, ou "" para as demais.
+func tabelaDynamo(entrada any) string {
+	switch e := entrada.(type) {
+	case *dynamodb.PutItemInput:
+		return aws.ToString(e.TableName)
+	case *dynamodb.QueryInput:
+		return aws.ToString(e.TableName)
+	case *dynamodb.ScanInput:
+		return aws.ToString(e.TableName)
+	case *dynamodb.UpdateItemInput:
+		return aws.ToString(e.TableName)
+	case *dynamodb.DeleteItemInput:
+		return aws.ToString(e.TableName)
+	case *dynamodb.DescribeTableInput:
+		return aws.ToString(e.TableName)
+	default:
+		return ""
+	}
+}
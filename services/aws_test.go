@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// secretsManagerLocal sobe um servidor que responde como o Secrets Manager e
+// aponta o SDK para ele, com credenciais fixas.
+func secretsManagerLocal(tb testing.TB) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{"Name":"fixer-api-key-dev","SecretString":"{\"fixer_api_key\":\"chave\"}"}`))
+	}))
+	tb.Cleanup(srv.Close)
+
+	tb.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	tb.Setenv("AWS_ACCESS_KEY_ID", "teste")
+	tb.Setenv("AWS_SECRET_ACCESS_KEY", "teste")
+	tb.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	tb.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	tb.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+}
+
+func TestNovosClientesAWS(t *testing.T) {
+	secretsManagerLocal(t)
+
+	clientes, err := services.NovosClientesAWS(ctx, config.AWS{Regiao: "sa-east-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sa-east-1", clientes.Config.Region)
+	assert.NotNil(t, clientes.DynamoDB)
+
+	key, err := services.NovoSecretsManagerSource(clientes.SecretsManager, "fixer-api-key-dev").APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "chave", key)
+}
+
+func TestNovosClientesAWS_SpanPorOperacao(t *testing.T) {
+	secretsManagerLocal(t)
+	gravador := gravarSpans(t)
+	clientes, err := services.NovosClientesAWS(ctx, config.AWS{Regiao: "sa-east-1"})
+	assert.NoError(t, err)
+
+	ctxPai, pai := otel.Tracer("teste").Start(ctx, "atualizar")
+	services.NovoSecretsManagerSource(clientes.SecretsManager, "fixer-api-key-dev").APIKey(ctxPai)
+	repository.NovoDynamoRepository(clientes.DynamoDB, "CotacoesPorPar").Latest(ctxPai, "BRL", "USD")
+	pai.End()
+
+	segredo := spanChamado(t, gravador, "Secrets Manager.GetSecretValue")
+	assert.Equal(t, trace.SpanKindClient, segredo.SpanKind())
+	assert.Equal(t, pai.SpanContext().SpanID(), segredo.Parent().SpanID())
+
+	consulta := spanChamado(t, gravador, "DynamoDB.Query")
+	assert.Equal(t, pai.SpanContext().SpanID(), consulta.Parent().SpanID())
+	assert.Contains(t, consulta.Attributes(), attribute.String("db.system", "dynamodb"))
+	assert.Contains(t, consulta.Attributes(), attribute.StringSlice("aws.dynamodb.table_names", []string{"CotacoesPorPar"}))
+	assert.Contains(t, consulta.Attributes(), attribute.String("cloud.region", "sa-east-1"))
+}
+
+// Compara buscar a chave com os clientes criados uma única vez e recriando a
+// configuração e os clientes a cada requisição, como era feito antes.
+func BenchmarkSecretsManager_ClientesCompartilhados(b *testing.B) {
+	secretsManagerLocal(b)
+	clientes, err := services.NovosClientesAWS(ctx, config.AWS{Regiao: "us-east-1"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	source := services.NovoSecretsManagerSource(clientes.SecretsManager, "fixer-api-key-dev")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := source.APIKey(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSecretsManager_ClientesPorRequisicao(b *testing.B) {
+	secretsManagerLocal(b)
+
+	for i := 0; i < b.N; i++ {
+		clientes, err := services.NovosClientesAWS(ctx, config.AWS{Regiao: "us-east-1"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := services.NovoSecretsManagerSource(clientes.SecretsManager, "fixer-api-key-dev").APIKey(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
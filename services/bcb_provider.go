@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// diasBuscaPTAX é quantos dias para trás são consultados, para cobrir fins de
+// semana e feriados sem boletim.
+const diasBuscaPTAX = 7
+
+// fusoBrasilia é o fuso dos horários dos boletins PTAX.
+var fusoBrasilia = carregarFuso("America/Sao_Paulo")
+
+// carregarFuso carrega o fuso nome. A base de fusos vai embutida no binário
+// pelo pacote config (time/tzdata), então a falha só acontece com um nome inválido, um erro de
+// programação que deve impedir o processo de subir.
+func carregarFuso(nome string) *time.Location {
+	fuso, err := time.LoadLocation(nome)
+	if err != nil {
+		panic(fmt.Sprintf("fuso %s indisponível: %v", nome, err))
+	}
+	return fuso
+}
+
+type ptaxResponse struct {
+	Value []struct {
+		CotacaoVenda    decimal.Decimal `json:"cotacaoVenda"`
+		DataHoraCotacao string          `json:"dataHoraCotacao"`
+	} `json:"value"`
+}
+
+// BCBProvider busca a taxa PTAX de venda publicada pelo Banco Central. A PTAX
+// é sempre cotada em reais por unidade de moeda estrangeira; pares sem BRL são
+// calculados pela taxa cruzada.
+type BCBProvider struct {
+	URL    string
+	Client HTTPClient
+}
+
+// NovoBCBProvider cria o provedor para a API em url.
+func NovoBCBProvider(url string, client HTTPClient) *BCBProvider {
+	return &BCBProvider{URL: url, Client: client}
+}
+
+func (p *BCBProvider) Nome() string { return "bcb" }
+
+// BuscarTaxas calcula as taxas pelos boletins PTAX de base e de cada
+// símbolo. Cada moeda tem o próprio boletim, então o horário vai por símbolo
+// em DatasHoras: o do boletim mais recente entre os dois usados na taxa, já
+// que a publicação de qualquer um deles muda o valor.
+func (p *BCBProvider) BuscarTaxas(ctx context.Context, base string, simbolos []string) (Taxas, error) {
+	ptaxBase, dataHoraBase, err := p.buscarPTAX(ctx, base)
+	if err != nil {
+		return Taxas{}, err
+	}
+
+	taxas := Taxas{
+		Base:       base,
+		Rates:      make(map[string]decimal.Decimal, len(simbolos)),
+		DatasHoras: make(map[string]time.Time, len(simbolos)),
+	}
+	for _, simbolo := range simbolos {
+		ptax, dataHora, err := p.buscarPTAX(ctx, simbolo)
+		if err != nil {
+			return Taxas{}, err
+		}
+		taxas.Rates[simbolo] = ptaxBase.DivRound(ptax, casasDecimaisTaxa)
+		if dataHoraBase.After(dataHora) {
+			dataHora = dataHoraBase
+		}
+		taxas.DatasHoras[simbolo] = dataHora
+	}
+	return taxas, nil
+}
+
+// buscarPTAX retorna quantos reais vale uma unidade de moeda, segundo o
+// boletim PTAX mais recente dos últimos diasBuscaPTAX dias, e o horário do
+// boletim (zero para BRL ou se a API não o informar).
+func (p *BCBProvider) buscarPTAX(ctx context.Context, moeda string) (decimal.Decimal, time.Time, error) {
+	if moeda == "BRL" {
+		return decimal.NewFromInt(1), time.Time{}, nil
+	}
+
+	fim := time.Now()
+	inicio := fim.AddDate(0, 0, -diasBuscaPTAX)
+	layout := "01-02-2006" // formato exigido pela API: MM-DD-AAAA
+
+	url := fmt.Sprintf("%s/CotacaoMoedaPeriodo(moeda=@moeda,dataInicial=@dataInicial,dataFinalCotacao=@dataFinalCotacao)"+
+		"?@moeda='%s'&@dataInicial='%s'&@dataFinalCotacao='%s'&$orderby=dataHoraCotacao%%20desc&$top=1&$format=json",
+		p.URL, moeda, inicio.Format(layout), fim.Format(layout))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+
+	var resp ptaxResponse
+	if err := buscarJSON(p.Client, req, &resp); err != nil {
+		return decimal.Decimal{}, time.Time{}, err
+	}
+
+	if len(resp.Value) == 0 || !resp.Value[0].CotacaoVenda.IsPositive() {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("nenhuma cotação PTAX encontrada para %s", moeda)
+	}
+
+	if resp.Value[0].DataHoraCotacao == "" {
+		return resp.Value[0].CotacaoVenda, time.Time{}, nil
+	}
+	// dataHoraCotacao vem no horário de Brasília, sem fuso: "2025-04-17 13:09:26.421"
+	dataHora, err := time.ParseInLocation("2006-01-02 15:04:05.999", resp.Value[0].DataHoraCotacao, fusoBrasilia)
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, fmt.Errorf("horário inválido no boletim PTAX de %s: %w", moeda, err)
+	}
+	return resp.Value[0].CotacaoVenda, dataHora.UTC(), nil
+}
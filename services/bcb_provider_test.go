@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"cambio-brl-usd/services"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func novoServidorPTAX(t *testing.T, ptax map[string]float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.URL.Path, "/CotacaoMoedaPeriodo("))
+		moeda := strings.Trim(r.URL.Query().Get("@moeda"), "'")
+		valor, ok := ptax[moeda]
+		if !ok {
+			w.Write([]byte(`{"value":[]}`))
+			return
+		}
+		fmt.Fprintf(w, `{"value":[{"cotacaoCompra":%[1]f,"cotacaoVenda":%[1]f,"dataHoraCotacao":"2025-04-17 13:08:28.85"}]}`, valor)
+	}))
+}
+
+func TestBCBProvider_BuscarTaxas_BaseBRL(t *testing.T) {
+	srv := novoServidorPTAX(t, map[string]float64{"USD": 5.0, "EUR": 6.25})
+	defer srv.Close()
+
+	taxas, err := services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "BRL", []string{"USD", "EUR"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "BRL", taxas.Base)
+	assert.Equal(t, "0.2", taxas.Rates["USD"].String())
+	assert.Equal(t, "0.16", taxas.Rates["EUR"].String())
+	// 13:08:28.85 em Brasília
+	assert.Equal(t, time.Date(2025, 4, 17, 16, 8, 28, 850000000, time.UTC), taxas.DataHoraDe("USD"))
+}
+
+func TestBCBProvider_BuscarTaxas_TaxaCruzada(t *testing.T) {
+	srv := novoServidorPTAX(t, map[string]float64{"USD": 5.0, "EUR": 6.25})
+	defer srv.Close()
+
+	taxas, err := services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "USD", []string{"EUR", "BRL"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8", taxas.Rates["EUR"].String())
+	assert.Equal(t, "5", taxas.Rates["BRL"].String())
+}
+
+func TestBCBProvider_BuscarTaxas_HorarioPorMoeda(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		horario := map[string]string{"'USD'": "2025-04-17 13:08:28.85", "'EUR'": "2025-04-16 13:06:00.0"}[r.URL.Query().Get("@moeda")]
+		fmt.Fprintf(w, `{"value":[{"cotacaoCompra":5.0,"cotacaoVenda":5.0,"dataHoraCotacao":%q}]}`, horario)
+	}))
+	defer srv.Close()
+
+	taxas, err := services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "BRL", []string{"USD", "EUR"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 4, 17, 16, 8, 28, 850000000, time.UTC), taxas.DataHoraDe("USD"))
+	assert.Equal(t, time.Date(2025, 4, 16, 16, 6, 0, 0, time.UTC), taxas.DataHoraDe("EUR"))
+
+	// Na taxa cruzada vale o boletim mais recente dos dois
+	taxas, err = services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "EUR", []string{"USD", "BRL"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 4, 17, 16, 8, 28, 850000000, time.UTC), taxas.DataHoraDe("USD"))
+	assert.Equal(t, time.Date(2025, 4, 16, 16, 6, 0, 0, time.UTC), taxas.DataHoraDe("BRL"))
+}
+
+func TestBCBProvider_MoedaSemPTAX(t *testing.T) {
+	srv := novoServidorPTAX(t, map[string]float64{"USD": 5.0})
+	defer srv.Close()
+
+	_, err := services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "BRL", []string{"ARS"})
+	assert.ErrorContains(t, err, "ARS")
+}
+
+func TestBCBProvider_Nome(t *testing.T) {
+	assert.Equal(t, "bcb", services.NovoBCBProvider("", http.DefaultClient).Nome())
+}
+
+func TestBCBProvider_HorarioInvalido(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"value":[{"cotacaoCompra":5.0,"cotacaoVenda":5.0,"dataHoraCotacao":"17/04/2025 13:08"}]}`))
+	}))
+	defer srv.Close()
+
+	_, err := services.NovoBCBProvider(srv.URL, srv.Client()).BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.ErrorContains(t, err, "horário inválido no boletim PTAX de USD")
+}
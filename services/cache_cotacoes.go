@@ -0,0 +1,67 @@
+package services
+
+import (
+	"cambio-brl-usd/models"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheCotacoes guarda a última cotação obtida dos provedores para cada par e
+// a serve até validade depois de obtida. grupo garante que requisições
+// simultâneas pelas mesmas moedas façam uma única consulta aos provedores.
+type cacheCotacoes struct {
+	validade time.Duration
+	grupo    singleflight.Group
+
+	mu       sync.RWMutex
+	cotacoes map[string]cotacaoEmCache
+}
+
+// cotacaoEmCache guarda quando a cotação foi obtida, já que DataHora é o
+// horário informado pelo provedor e pode ser bem mais antigo.
+type cotacaoEmCache struct {
+	cotacao  models.Cotacao
+	obtidaEm time.Time
+}
+
+func novoCacheCotacoes(validade time.Duration) *cacheCotacoes {
+	return &cacheCotacoes{validade: validade, cotacoes: map[string]cotacaoEmCache{}}
+}
+
+// buscar devolve a cotação de origem para cada destino se todas estiverem em
+// cache e dentro da validade em agora.
+func (c *cacheCotacoes) buscar(origem string, destinos []string, agora time.Time) ([]models.Cotacao, bool) {
+	if c.validade <= 0 {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cotacoes := make([]models.Cotacao, 0, len(destinos))
+	for _, destino := range destinos {
+		emCache, ok := c.cotacoes[origem+"#"+destino]
+		if !ok || agora.Sub(emCache.obtidaEm) >= c.validade {
+			return nil, false
+		}
+		cotacoes = append(cotacoes, emCache.cotacao)
+	}
+	return cotacoes, true
+}
+
+// guardar substitui as cotações em cache dos pares de cotacoes, obtidas em
+// agora.
+func (c *cacheCotacoes) guardar(cotacoes []models.Cotacao, agora time.Time) {
+	if c.validade <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cotacao := range cotacoes {
+		c.cotacoes[cotacao.MoedaOrigem+"#"+cotacao.MoedaDestino] = cotacaoEmCache{cotacao: cotacao, obtidaEm: agora}
+	}
+}
@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/repository"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixerContador responde como o Fixer e conta as requisições recebidas.
+func fixerContador(t *testing.T, consultas *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		consultas.Add(1)
+		w.Write([]byte(`{"success":true,"base":"BRL","rates":{"USD":0.18,"EUR":0.16}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAtualizarCotacoes_ServeDaMemoriaDentroDaValidade(t *testing.T) {
+	var consultas atomic.Int32
+	srv := fixerContador(t, &consultas)
+	repo := repository.NovoMemoryRepository()
+	r := &relogio{agora: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)}
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.repo = repo
+		d.agora = r.Now
+	})
+	acertos := testutil.ToFloat64(metricas.Cache.WithLabelValues("acerto"))
+	faltas := testutil.ToFloat64(metricas.Cache.WithLabelValues("falta"))
+
+	primeira, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+
+	r.agora = r.agora.Add(59 * time.Second)
+	segunda, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, primeira, segunda)
+	assert.EqualValues(t, 1, consultas.Load())
+	assert.Equal(t, acertos+1, testutil.ToFloat64(metricas.Cache.WithLabelValues("acerto")))
+	assert.Equal(t, faltas+1, testutil.ToFloat64(metricas.Cache.WithLabelValues("falta")))
+
+	// Par ainda sem cotação em memória
+	_, err = svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "EUR"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, consultas.Load())
+
+	r.agora = r.agora.Add(time.Minute)
+	terceira, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, r.agora, terceira[0].DataHora)
+	assert.EqualValues(t, 3, consultas.Load())
+
+	historico, _ := repo.Range(ctx, "BRL", "USD", time.Time{}, r.agora)
+	assert.Len(t, historico, 3)
+}
+
+func TestAtualizarCotacoes_ValidadeContaDaBuscaNaoDoProvedor(t *testing.T) {
+	var consultas atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		consultas.Add(1)
+		// Taxa publicada um dia antes da busca
+		w.Write([]byte(`{"success":true,"timestamp":1745150400,"base":"BRL","rates":{"USD":0.18}}`))
+	}))
+	defer srv.Close()
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.agora = r.Now
+	})
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+	r.agora = r.agora.Add(30 * time.Second)
+	_, err = svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 1, consultas.Load())
+}
+
+func TestAtualizarCotacoes_SemCache(t *testing.T) {
+	var consultas atomic.Int32
+	srv := fixerContador(t, &consultas)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.cfg.Cotacao.Validade = 0
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 3, consultas.Load())
+}
+
+func TestAtualizarCotacoes_ChamadasSimultaneasFazemUmaConsulta(t *testing.T) {
+	var consultas atomic.Int32
+	chegou := make(chan struct{}, 1)
+	liberar := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		consultas.Add(1)
+		chegou <- struct{}{}
+		<-liberar
+		w.Write([]byte(`{"success":true,"base":"BRL","rates":{"USD":0.18}}`))
+	}))
+	defer srv.Close()
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = srv.URL })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+			assert.NoError(t, err)
+			assert.Equal(t, "0.18", cotacoes[0].Valor.String())
+		}()
+	}
+
+	<-chegou
+	time.Sleep(20 * time.Millisecond) // deixa as demais chamadas aguardarem a primeira
+	close(liberar)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, consultas.Load())
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/rastreamento"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClienteResiliente envolve o HTTPClient de um provedor com timeout por
+// tentativa, novas tentativas com espera exponencial aleatória para erros de
+// rede, 5xx e 429, um circuito que para de chamar o provedor depois de
+// FalhasCircuito requisições seguidas com falha e o respeito aos cabeçalhos
+// de limite de requisições do apilayer. É seguro para uso concorrente.
+type ClienteResiliente struct {
+	Nome           string
+	Client         HTTPClient
+	Timeout        time.Duration
+	Tentativas     int
+	EsperaInicial  time.Duration
+	EsperaMaxima   time.Duration
+	FalhasCircuito int
+	PausaCircuito  time.Duration
+
+	agora func() time.Time
+
+	mu          sync.Mutex
+	falhas      int       // requisições seguidas com falha
+	abertoAte   time.Time // fim da pausa do circuito aberto
+	testando    bool      // circuito meio aberto, com uma requisição de teste em andamento
+	limitadoAte time.Time // fim da espera pela renovação da cota do provedor
+}
+
+// NovoClienteResiliente cria o cliente do provedor nome com os limites de
+// cfg, usando agora para medir as pausas.
+func NovoClienteResiliente(nome string, client HTTPClient, cfg config.Provedores, agora func() time.Time) *ClienteResiliente {
+	return &ClienteResiliente{
+		Nome:           nome,
+		Client:         client,
+		Timeout:        time.Duration(cfg.Timeout),
+		Tentativas:     cfg.Tentativas,
+		EsperaInicial:  time.Duration(cfg.EsperaInicial),
+		EsperaMaxima:   time.Duration(cfg.EsperaMaxima),
+		FalhasCircuito: cfg.FalhasCircuito,
+		PausaCircuito:  time.Duration(cfg.PausaCircuito),
+		agora:          agora,
+	}
+}
+
+// Do envia req, repetindo-a quando vale a pena. Com o circuito aberto ou a
+// cota esgotada, retorna ErrCircuitoAberto ou ErrLimiteRequisicoes sem chamar
+// o provedor. A resposta da última tentativa é devolvida como veio, mesmo com
+// status de erro, para que o provedor a interprete. Toda a chamada, com as
+// novas tentativas, fica no span "provedor <nome>".
+func (c *ClienteResiliente) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := rastreamento.Iniciar(req.Context(), "provedor "+c.Nome, trace.WithAttributes(attribute.String("provedor", c.Nome)))
+	req = req.WithContext(ctx)
+
+	if err := c.liberar(); err != nil {
+		recusa := "circuito_aberto"
+		if errors.Is(err, ErrLimiteRequisicoes) {
+			recusa = "cota_esgotada"
+		}
+		metricas.ChamadasProvedor.WithLabelValues(c.Nome, recusa).Inc()
+		span.SetAttributes(attribute.String("resultado", recusa))
+		rastreamento.Finalizar(span, err)
+		return nil, err
+	}
+
+	resp, err := c.tentar(req)
+	c.registrar(req, resp, err)
+
+	falha := err
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		falha = errors.New(motivo(resp, nil))
+	}
+	span.SetAttributes(attribute.String("resultado", resultadoChamada(resp, err)))
+	rastreamento.Finalizar(span, falha)
+	return resp, err
+}
+
+// liberar decide se a requisição pode seguir. Depois da pausa, o circuito
+// fica meio aberto: uma única requisição passa e, conforme o resultado, o
+// circuito fecha ou abre de novo.
+func (c *ClienteResiliente) liberar() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	agora := c.agora()
+	if agora.Before(c.limitadoAte) {
+		return fmt.Errorf("%w: %s até %s", ErrLimiteRequisicoes, c.Nome, c.limitadoAte.Format(time.RFC3339))
+	}
+	if c.FalhasCircuito == 0 || c.falhas < c.FalhasCircuito {
+		return nil
+	}
+	if agora.Before(c.abertoAte) || c.testando {
+		return fmt.Errorf("%w: %s após %d falhas seguidas", ErrCircuitoAberto, c.Nome, c.falhas)
+	}
+	c.testando = true
+	return nil
+}
+
+// registrar conta o resultado da requisição para o circuito. Requisições
+// canceladas por quem chamou não contam.
+func (c *ClienteResiliente) registrar(req *http.Request, resp *http.Response, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.testando = false
+	if req.Context().Err() != nil {
+		return
+	}
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		c.falhas = 0
+		return
+	}
+
+	c.falhas++
+	if c.FalhasCircuito > 0 && c.falhas >= c.FalhasCircuito {
+		c.abertoAte = c.agora().Add(c.PausaCircuito)
+		slog.WarnContext(req.Context(), "circuito do provedor aberto", "provedor", c.Nome, "pausa", c.PausaCircuito.String(), "falhas", c.falhas)
+	}
+}
+
+// tentar envia req até Tentativas vezes. Uma requisição com corpo que não
+// pode ser relido (sem GetBody) é enviada uma única vez.
+func (c *ClienteResiliente) tentar(req *http.Request) (*http.Response, error) {
+	tentativas := c.Tentativas
+	if req.Body != nil && req.GetBody == nil {
+		tentativas = 1
+	}
+
+	for tentativa := 1; ; tentativa++ {
+		resp, err := c.enviar(req)
+		espera, repetir := c.avaliar(req.Context(), resp, err, tentativa)
+		if !repetir || tentativa >= tentativas || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slog.WarnContext(req.Context(), "tentativa ao provedor falhou",
+			"provedor", c.Nome, "tentativa", tentativa, "tentativas", tentativas, "motivo", motivo(resp, err), "espera", espera.String())
+		trace.SpanFromContext(req.Context()).AddEvent("tentativa falhou", trace.WithAttributes(
+			attribute.Int("tentativa", tentativa), attribute.String("motivo", motivo(resp, err))))
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(espera):
+		}
+	}
+}
+
+// enviar faz uma tentativa com o prazo de Timeout e a registra no log em
+// nível debug. O prazo só é liberado
+// quando o corpo da resposta é fechado, para não interromper a leitura.
+func (c *ClienteResiliente) enviar(req *http.Request) (*http.Response, error) {
+	ctx, cancelar := context.WithTimeout(req.Context(), c.Timeout)
+	tentativa := req.Clone(ctx)
+	if req.GetBody != nil {
+		corpo, err := req.GetBody()
+		if err != nil {
+			cancelar()
+			return nil, err
+		}
+		tentativa.Body = corpo
+	}
+
+	inicio := time.Now()
+	resp, err := c.Client.Do(tentativa)
+	slog.DebugContext(ctx, "chamada ao provedor", "provedor", c.Nome, "host", req.URL.Host,
+		"resultado", motivo(resp, err), "duracao_ms", time.Since(inicio).Milliseconds())
+	resultado := resultadoChamada(resp, err)
+	metricas.ChamadasProvedor.WithLabelValues(c.Nome, resultado).Inc()
+	metricas.DuracaoProvedor.WithLabelValues(c.Nome, resultado).Observe(metricas.Desde(inicio))
+	if err != nil {
+		cancelar()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			return nil, fmt.Errorf("provedor %s não respondeu em %s: %w", c.Nome, c.Timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = corpoComPrazo{ReadCloser: resp.Body, cancelar: cancelar}
+	return resp, nil
+}
+
+// avaliar decide se a tentativa deve ser repetida e depois de quanto tempo.
+// Quando os cabeçalhos de limite mostram a cota esgotada, o provedor deixa de
+// ser chamado até ela renovar; um 429 só é repetido se a renovação vier dentro
+// de EsperaMaxima.
+func (c *ClienteResiliente) avaliar(ctx context.Context, resp *http.Response, err error, tentativa int) (time.Duration, bool) {
+	if err != nil {
+		return c.espera(tentativa), true
+	}
+
+	esgotada, renovacao := lerLimite(resp.Header, c.agora())
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if renovacao > 0 && renovacao <= c.EsperaMaxima {
+			return renovacao, true
+		}
+		if renovacao == 0 {
+			return c.espera(tentativa), true
+		}
+		esgotada = true
+	}
+	if esgotada {
+		c.limitar(ctx, renovacao)
+		return 0, false
+	}
+
+	return c.espera(tentativa), resp.StatusCode >= http.StatusInternalServerError
+}
+
+// limitar suspende as chamadas ao provedor por renovacao ou, se o provedor
+// não informar quando a cota renova, por PausaCircuito.
+func (c *ClienteResiliente) limitar(ctx context.Context, renovacao time.Duration) {
+	if renovacao <= 0 {
+		renovacao = c.PausaCircuito
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limitadoAte = c.agora().Add(renovacao)
+	slog.WarnContext(ctx, "cota de requisições do provedor esgotada", "provedor", c.Nome, "renovacao", renovacao.String())
+}
+
+// espera é o intervalo antes da próxima tentativa: EsperaInicial dobrada a
+// cada tentativa, limitada a EsperaMaxima, com metade do valor sorteada para
+// que clientes simultâneos não repitam juntos.
+func (c *ClienteResiliente) espera(tentativa int) time.Duration {
+	espera := c.EsperaMaxima
+	if tentativa < 32 && c.EsperaInicial<<(tentativa-1) < c.EsperaMaxima {
+		espera = c.EsperaInicial << (tentativa - 1)
+	}
+	return espera/2 + rand.N(espera/2+1)
+}
+
+// Cabeçalhos de limite enviados pelo apilayer (e outras APIs): a cota que
+// resta em cada janela e em quanto tempo ela renova.
+var (
+	cabecalhosRestante = []string{
+		"RateLimit-Remaining",
+		"X-RateLimit-Remaining",
+		"X-RateLimit-Remaining-Minute",
+		"X-RateLimit-Remaining-Day",
+		"X-RateLimit-Remaining-Month",
+	}
+	cabecalhosRenovacao = []string{"Retry-After", "RateLimit-Reset", "X-RateLimit-Reset"}
+)
+
+// lerLimite informa se algum cabeçalho de cota restante está zerado e quanto
+// falta para a cota renovar (zero se nenhum cabeçalho disser). Retry-After
+// aceita segundos ou data HTTP; os demais, segundos.
+func lerLimite(cabecalho http.Header, agora time.Time) (bool, time.Duration) {
+	esgotada := false
+	for _, nome := range cabecalhosRestante {
+		if cabecalho.Get(nome) == "0" {
+			esgotada = true
+		}
+	}
+
+	for _, nome := range cabecalhosRenovacao {
+		valor := cabecalho.Get(nome)
+		if valor == "" {
+			continue
+		}
+		if segundos, err := strconv.Atoi(valor); err == nil && segundos > 0 {
+			return esgotada, time.Duration(segundos) * time.Second
+		}
+		if data, err := http.ParseTime(valor); err == nil && data.After(agora) {
+			return esgotada, data.Sub(agora)
+		}
+	}
+	return esgotada, 0
+}
+
+// resultadoChamada resume a tentativa no rótulo das métricas: "sucesso",
+// "status_4xx", "status_5xx", "status_429", "timeout", "cancelada" ou "erro"
+// (falha de rede).
+func resultadoChamada(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return metricas.Resultado(err)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "status_429"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "status_5xx"
+	case resp.StatusCode >= http.StatusBadRequest:
+		return "status_4xx"
+	default:
+		return "sucesso"
+	}
+}
+
+// motivo descreve a falha de uma tentativa para o log.
+func motivo(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+// corpoComPrazo libera o prazo da tentativa quando o corpo é fechado.
+type corpoComPrazo struct {
+	io.ReadCloser
+	cancelar context.CancelFunc
+}
+
+func (c corpoComPrazo) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancelar()
+	return err
+}
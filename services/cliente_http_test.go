@@ -0,0 +1,307 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/rastreamento"
+	"cambio-brl-usd/services"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// servidorComRespostas responde com os status de status, um por chamada,
+// repetindo o último; chamadas conta as requisições recebidas.
+func servidorComRespostas(t *testing.T, chamadas *atomic.Int32, cabecalho http.Header, status ...int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		i := int(chamadas.Add(1)) - 1
+		for nome, valores := range cabecalho {
+			w.Header()[nome] = valores
+		}
+		w.WriteHeader(status[min(i, len(status)-1)])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// novoClienteResiliente cria o cliente com espera mínima entre tentativas e
+// o relógio r.
+func novoClienteResiliente(r *relogio, alterar func(*config.Provedores)) *services.ClienteResiliente {
+	cfg := config.Padrao().Provedores
+	cfg.EsperaInicial = config.Duracao(time.Millisecond)
+	cfg.EsperaMaxima = config.Duracao(10 * time.Millisecond)
+	if alterar != nil {
+		alterar(&cfg)
+	}
+	return services.NovoClienteResiliente("teste", http.DefaultClient, cfg, r.Now)
+}
+
+func get(t *testing.T, c services.HTTPClient, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	resp, err := c.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestClienteResiliente_RepeteErroDoServidor(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 503, 502, 200)
+
+	resp, err := get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.EqualValues(t, 3, chamadas.Load())
+}
+
+func TestClienteResiliente_DevolveUltimaRespostaAoEsgotarTentativas(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 500)
+
+	resp, err := get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.EqualValues(t, 3, chamadas.Load())
+}
+
+func TestClienteResiliente_MedeCadaTentativa(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 503, 200)
+	falhas := testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "status_5xx"))
+	sucessos := testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "sucesso"))
+
+	_, err := get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, falhas+1, testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "status_5xx")))
+	assert.Equal(t, sucessos+1, testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "sucesso")))
+}
+
+func TestClienteResiliente_SpanComAsTentativas(t *testing.T) {
+	gravador := gravarSpans(t)
+	var (
+		mu           sync.Mutex
+		traceparents []string
+	)
+	status := []int{503, 200}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		w.WriteHeader(status[len(traceparents)-1])
+	}))
+	defer srv.Close()
+	cliente := novoClienteResiliente(&relogio{}, nil)
+	cliente.Client = rastreamento.ClienteHTTP()
+
+	_, err := get(t, cliente, srv.URL)
+
+	assert.NoError(t, err)
+	span := spanChamado(t, gravador, "provedor teste")
+	assert.Equal(t, codes.Unset, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attribute.String("resultado", "sucesso"))
+	assert.Len(t, span.Events(), 1)
+	assert.Equal(t, "tentativa falhou", span.Events()[0].Name)
+	assert.Len(t, traceparents, 2)
+	for _, traceparent := range traceparents {
+		assert.Contains(t, traceparent, span.SpanContext().TraceID().String(), "cada tentativa leva o trace ao provedor")
+	}
+}
+
+func TestClienteResiliente_SpanComErroDoProvedor(t *testing.T) {
+	gravador := gravarSpans(t)
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 401)
+
+	get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	span := spanChamado(t, gravador, "provedor teste")
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, "status 401", span.Status().Description)
+	assert.Contains(t, span.Attributes(), attribute.String("resultado", "status_4xx"))
+}
+
+func TestClienteResiliente_NaoRepeteErroDoCliente(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 401)
+
+	resp, err := get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
+	assert.EqualValues(t, 1, chamadas.Load())
+}
+
+func TestClienteResiliente_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+	cliente := novoClienteResiliente(&relogio{}, func(cfg *config.Provedores) {
+		cfg.Timeout = config.Duracao(20 * time.Millisecond)
+		cfg.Tentativas = 2
+	})
+
+	inicio := time.Now()
+	_, err := get(t, cliente, srv.URL)
+
+	assert.ErrorContains(t, err, "provedor teste não respondeu em 20ms")
+	assert.Less(t, time.Since(inicio), 500*time.Millisecond)
+}
+
+func TestClienteResiliente_CircuitoAbreEFechaDepoisDaPausa(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 500, 500, 200)
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	cliente := novoClienteResiliente(r, func(cfg *config.Provedores) {
+		cfg.Tentativas = 1
+		cfg.FalhasCircuito = 2
+		cfg.PausaCircuito = config.Duracao(30 * time.Second)
+	})
+
+	barradas := testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "circuito_aberto"))
+	get(t, cliente, srv.URL)
+	get(t, cliente, srv.URL)
+	_, err := get(t, cliente, srv.URL)
+	assert.ErrorIs(t, err, services.ErrCircuitoAberto)
+	assert.EqualValues(t, 2, chamadas.Load())
+	assert.Equal(t, barradas+1, testutil.ToFloat64(metricas.ChamadasProvedor.WithLabelValues("teste", "circuito_aberto")))
+
+	// Depois da pausa uma requisição de teste passa e, com sucesso, fecha o
+	// circuito
+	r.agora = r.agora.Add(31 * time.Second)
+	resp, err := get(t, cliente, srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	_, err = get(t, cliente, srv.URL)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, chamadas.Load())
+}
+
+func TestClienteResiliente_FalhaNoTesteReabreCircuito(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 500)
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	cliente := novoClienteResiliente(r, func(cfg *config.Provedores) {
+		cfg.Tentativas = 1
+		cfg.FalhasCircuito = 1
+	})
+
+	get(t, cliente, srv.URL)
+	r.agora = r.agora.Add(time.Minute)
+	get(t, cliente, srv.URL)
+	_, err := get(t, cliente, srv.URL)
+
+	assert.ErrorIs(t, err, services.ErrCircuitoAberto)
+	assert.EqualValues(t, 2, chamadas.Load())
+}
+
+func TestClienteResiliente_RepeteTooManyRequestsSemRenovacao(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, nil, 429, 200)
+
+	resp, err := get(t, novoClienteResiliente(&relogio{}, nil), srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.EqualValues(t, 2, chamadas.Load())
+}
+
+func TestClienteResiliente_RetryAfterLongoSuspendeProvedor(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, http.Header{"Retry-After": {"3600"}}, 429)
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	cliente := novoClienteResiliente(r, nil)
+
+	resp, err := get(t, cliente, srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 429, resp.StatusCode)
+
+	_, err = get(t, cliente, srv.URL)
+	assert.ErrorIs(t, err, services.ErrLimiteRequisicoes)
+	assert.EqualValues(t, 1, chamadas.Load())
+
+	r.agora = r.agora.Add(time.Hour)
+	get(t, cliente, srv.URL)
+	assert.EqualValues(t, 2, chamadas.Load())
+}
+
+func TestClienteResiliente_CotaZeradaNosCabecalhos(t *testing.T) {
+	var chamadas atomic.Int32
+	srv := servidorComRespostas(t, &chamadas, http.Header{
+		"X-Ratelimit-Remaining-Day": {"0"},
+		"Ratelimit-Reset":           {"120"},
+	}, 200)
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	cliente := novoClienteResiliente(r, nil)
+
+	resp, err := get(t, cliente, srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	_, err = get(t, cliente, srv.URL)
+	assert.ErrorIs(t, err, services.ErrLimiteRequisicoes)
+
+	r.agora = r.agora.Add(2 * time.Minute)
+	_, err = get(t, cliente, srv.URL)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, chamadas.Load())
+}
+
+func TestAtualizarCotacoes_CircuitoAbertoPassaParaProximoProvedor(t *testing.T) {
+	var chamadas atomic.Int32
+	fixer := servidorComRespostas(t, &chamadas, nil, 500)
+	reserva := servidor(t, `{"base":"BRL","rates":{"USD":0.18}}`)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.Ordem = []string{"fixer", "exchangeratehost"}
+		d.cfg.Provedores.FixerURL = fixer.URL
+		d.cfg.Provedores.ExchangeRateURL = reserva.URL
+		d.cfg.Provedores.Tentativas = 1
+		d.cfg.Provedores.FalhasCircuito = 2
+		d.cfg.Cotacao.Validade = 0
+	})
+
+	for i := 0; i < 4; i++ {
+		cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+		assert.NoError(t, err)
+		assert.Equal(t, "exchangeratehost", cotacoes[0].Provedor)
+	}
+	assert.EqualValues(t, 2, chamadas.Load())
+}
+
+func TestAtualizarCotacoes_PausaDoCircuitoUsaORelogioDoServico(t *testing.T) {
+	var chamadas atomic.Int32
+	fixer := servidorComRespostas(t, &chamadas, nil, 500)
+	r := &relogio{agora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)}
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = fixer.URL
+		d.cfg.Provedores.Tentativas = 1
+		d.cfg.Provedores.FalhasCircuito = 1
+		d.cfg.Provedores.PausaCircuito = config.Duracao(30 * time.Second)
+		d.cfg.Cotacao.Validade = 0
+		d.agora = r.Now
+	})
+
+	svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.ErrorIs(t, err, services.ErrCircuitoAberto)
+
+	r.agora = r.agora.Add(31 * time.Second)
+	svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.EqualValues(t, 2, chamadas.Load())
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"cambio-brl-usd/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// casasDecimaisTaxa limita as casas das taxas inversas e cruzadas, que são
+// resultado de divisão.
+const casasDecimaisTaxa = 16
+
+// arredondamentos são os modos aceitos em Config.Conversao.Arredondamento.
+var arredondamentos = map[string]func(d decimal.Decimal, casas int32) decimal.Decimal{
+	"half_even": decimal.Decimal.RoundBank,
+	"half_up":   decimal.Decimal.Round,
+	"down":      decimal.Decimal.RoundDown,
+	"up":        decimal.Decimal.RoundUp,
+}
+
+// Converter converte valor de de para para com a cotação gravada mais recente
+// ou, se data não for zero, com a mais próxima de data. Sem cotação direta
+// entre as moedas, usa a inversa e depois a taxa cruzada por
+// Config.Moedas.Pivo. Converter uma moeda nela mesma usa taxa 1, sem
+// consultar o armazenamento, com DataHora igual a data ou, se ela for zero, ao
+// horário atual. O resultado é arredondado conforme Config.Conversao.
+func (s *CotacaoService) Converter(ctx context.Context, valor decimal.Decimal, de, para string, data time.Time) (models.Conversao, error) {
+	arredondar, ok := arredondamentos[s.cfg.Conversao.Arredondamento]
+	if !ok {
+		return models.Conversao{}, fmt.Errorf("%w: arredondamento desconhecido: %q", ErrConfiguracao, s.cfg.Conversao.Arredondamento)
+	}
+	casas := int32(s.cfg.Conversao.CasasDecimais)
+
+	if de == para {
+		if data.IsZero() {
+			data = s.agora()
+		}
+		return models.Conversao{
+			Valor:           valor,
+			De:              de,
+			Para:            para,
+			ValorConvertido: arredondar(valor, casas),
+			Taxa:            decimal.NewFromInt(1),
+			DataHora:        data,
+		}, nil
+	}
+
+	cotacao, err := s.cotacaoCalculada(ctx, de, para, data)
+	if err != nil {
+		return models.Conversao{}, err
+	}
+
+	return models.Conversao{
+		Valor:           valor,
+		De:              de,
+		Para:            para,
+		ValorConvertido: arredondar(valor.Mul(cotacao.Valor), casas),
+		Taxa:            cotacao.Valor,
+		DataHora:        cotacao.DataHora,
+		MoedaPivo:       cotacao.MoedaPivo,
+	}, nil
+}
+
+// cotacaoCalculada retorna a cotação de → para gravada ou, não havendo,
+// calculada a partir das gravadas: pela inversa de para → de e depois pela
+// taxa cruzada por Config.Moedas.Pivo.
+func (s *CotacaoService) cotacaoCalculada(ctx context.Context, de, para string, data time.Time) (models.Cotacao, error) {
+	cotacao, err := s.cotacaoDoPar(ctx, de, para, data)
+	if pivo := s.cfg.Moedas.Pivo; errors.Is(err, ErrNaoEncontrado) && de != pivo && para != pivo {
+		return s.cotacaoCruzada(ctx, de, pivo, para, data)
+	}
+	return cotacao, err
+}
+
+// cotacaoCruzada compõe de → pivo → para. A cotação resultante leva os
+// metadados da mais antiga das duas e, como ObtidaEm, a obtenção mais antiga.
+func (s *CotacaoService) cotacaoCruzada(ctx context.Context, de, pivo, para string, data time.Time) (models.Cotacao, error) {
+	primeira, err := s.cotacaoDoPar(ctx, de, pivo, data)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	segunda, err := s.cotacaoDoPar(ctx, pivo, para, data)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+
+	cruzada := primeira
+	if segunda.DataHora.Before(primeira.DataHora) {
+		cruzada = segunda
+	}
+	if obtidaPrimeira, obtidaSegunda := obtidaEm(primeira), obtidaEm(segunda); obtidaSegunda.Before(obtidaPrimeira) {
+		cruzada.ObtidaEm = &obtidaSegunda
+	} else {
+		cruzada.ObtidaEm = &obtidaPrimeira
+	}
+	cruzada.MoedaOrigem, cruzada.MoedaDestino, cruzada.MoedaPivo = de, para, pivo
+	cruzada.Valor = primeira.Valor.Mul(segunda.Valor).Round(casasDecimaisTaxa)
+	cruzada.Contingencia = primeira.Contingencia || segunda.Contingencia
+	return cruzada, nil
+}
+
+// cotacaoDoPar busca a cotação de → para gravada ou, não havendo, o inverso da
+// cotação para → de.
+func (s *CotacaoService) cotacaoDoPar(ctx context.Context, de, para string, data time.Time) (models.Cotacao, error) {
+	cotacao, err := s.cotacaoSalva(ctx, de, para, data)
+	if !errors.Is(err, ErrNaoEncontrado) {
+		return cotacao, err
+	}
+
+	inversa, err := s.cotacaoSalva(ctx, para, de, data)
+	if err != nil {
+		return models.Cotacao{}, err
+	}
+	if inversa.Valor.IsZero() {
+		return models.Cotacao{}, fmt.Errorf("%w: cotação de %s para %s é zero", ErrNaoEncontrado, para, de)
+	}
+	inversa.MoedaOrigem, inversa.MoedaDestino = de, para
+	inversa.Valor = decimal.NewFromInt(1).DivRound(inversa.Valor, casasDecimaisTaxa)
+	return inversa, nil
+}
+
+// cotacaoSalva retorna a cotação mais recente do par ou, se data não for
+// zero, a mais próxima de data.
+func (s *CotacaoService) cotacaoSalva(ctx context.Context, origem, destino string, data time.Time) (models.Cotacao, error) {
+	if data.IsZero() {
+		return s.BuscarUltimaCotacaoSalva(ctx, origem, destino)
+	}
+
+	ctx, concluir := s.operacao(ctx, "Closest")
+	cotacao, err := s.repo.Closest(ctx, origem, destino, data)
+	concluir(err)
+	if err != nil {
+		return models.Cotacao{}, erroArmazenamento(err)
+	}
+	return cotacao, nil
+}
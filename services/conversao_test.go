@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// cotacoesSalvas retorna um armazenamento com BRL → USD e BRL → EUR gravadas
+// em dois momentos.
+func cotacoesSalvas() *repository.MemoryRepository {
+	repo := repository.NovoMemoryRepository()
+	for _, c := range []models.Cotacao{
+		{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.2"), DataHora: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)},
+		{MoedaOrigem: "BRL", MoedaDestino: "EUR", Valor: decimal.RequireFromString("0.16"), DataHora: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)},
+		{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.25"), DataHora: time.Date(2025, 4, 22, 12, 0, 0, 0, time.UTC)},
+		{MoedaOrigem: "BRL", MoedaDestino: "EUR", Valor: decimal.RequireFromString("0.2"), DataHora: time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)},
+	} {
+		repo.Save(ctx, c)
+	}
+	return repo
+}
+
+func TestConverter_Direta(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = cotacoesSalvas() })
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("123.45"), "BRL", "USD", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "30.86", conversao.ValorConvertido.String()) // 30.8625, arredondamento bancário
+	assert.Equal(t, "0.25", conversao.Taxa.String())
+	assert.Equal(t, time.Date(2025, 4, 22, 12, 0, 0, 0, time.UTC), conversao.DataHora)
+	assert.Empty(t, conversao.MoedaPivo)
+}
+
+func TestConverter_Inversa(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = cotacoesSalvas() })
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("10"), "USD", "BRL", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "40", conversao.ValorConvertido.String())
+	assert.Equal(t, "4", conversao.Taxa.String())
+}
+
+func TestConverter_MesmaMoeda(t *testing.T) {
+	// Sem nenhuma cotação gravada
+	svc := novoService(t, nil)
+	data := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("10.456"), "USD", "USD", data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "10.46", conversao.ValorConvertido.String())
+	assert.Equal(t, "1", conversao.Taxa.String())
+	assert.Equal(t, data, conversao.DataHora)
+	assert.Empty(t, conversao.MoedaPivo)
+}
+
+func TestConverter_CruzadaPeloPivo(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = cotacoesSalvas() })
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("100"), "USD", "EUR", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "BRL", conversao.MoedaPivo)
+	assert.Equal(t, "0.8", conversao.Taxa.String())
+	assert.Equal(t, "80", conversao.ValorConvertido.String())
+	// A cotação mais antiga das duas usadas
+	assert.Equal(t, time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC), conversao.DataHora)
+}
+
+func TestConverter_CotacaoMaisProximaDaData(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = cotacoesSalvas() })
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("100"), "BRL", "USD", time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.2", conversao.Taxa.String())
+	assert.Equal(t, "20", conversao.ValorConvertido.String())
+}
+
+func TestConverter_ArredondamentoConfiguravel(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = cotacoesSalvas()
+		d.cfg.Conversao.CasasDecimais = 1
+		d.cfg.Conversao.Arredondamento = "up"
+	})
+
+	conversao, err := svc.Converter(ctx, decimal.RequireFromString("123.45"), "BRL", "USD", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "30.9", conversao.ValorConvertido.String())
+}
+
+func TestConverter_ArredondamentoInvalido(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = cotacoesSalvas()
+		d.cfg.Conversao.Arredondamento = "aleatorio"
+	})
+
+	_, err := svc.Converter(ctx, decimal.RequireFromString("1"), "BRL", "USD", time.Time{})
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestConverter_SemCotacao(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = cotacoesSalvas() })
+
+	_, err := svc.Converter(ctx, decimal.RequireFromString("1"), "BRL", "JPY", time.Time{})
+	assert.ErrorIs(t, err, services.ErrNaoEncontrado)
+
+	_, err = svc.Converter(ctx, decimal.RequireFromString("1"), "GBP", "USD", time.Time{})
+	assert.ErrorIs(t, err, services.ErrNaoEncontrado)
+}
+
+func TestConverter_ErroNoArmazenamento(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
+
+	_, err := svc.Converter(ctx, decimal.RequireFromString("1"), "BRL", "USD", time.Time{})
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
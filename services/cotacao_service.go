@@ -1,208 +1,356 @@
 package services
 
 import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
 	"cambio-brl-usd/models"
+	"cambio-brl-usd/rastreamento"
+	"cambio-brl-usd/repository"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type apiResponse struct {
-	Base    string             `json:"base"`
-	Success bool               `json:"success"`
-	Rates   map[string]float64 `json:"rates"`
+// HTTPClient executa as requisições aos provedores de cotações. *http.Client
+// satisfaz a interface.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
 }
 
-var (
-	NewHTTPRequest = http.NewRequest
-	HTTPClientDo   = (&http.Client{}).Do
-	DynamoScan     = func(c *dynamodb.Client, input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-		return c.Scan(context.TODO(), input)
-	}
-	UnmarshalList = attributevalue.UnmarshalListOfMaps
-)
-
-var PutItemFn = func(client *dynamodb.Client, input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
-	return client.PutItem(context.TODO(), input)
+// CotacaoService busca cotações nos provedores, grava e consulta o histórico.
+// Todas as dependências são recebidas em NovoCotacaoService e não mudam
+// depois; o único estado interno, o cache das últimas cotações, é protegido
+// por mutex, então o mesmo serviço pode ser usado por várias goroutines.
+type CotacaoService struct {
+	cfg      config.Config
+	repo     repository.CotacaoRepository
+	provider RateProvider
+	segredos SecretSource
+	agora    func() time.Time
+	cache    *cacheCotacoes
 }
 
-var GetSecretValueFn = func(svc *secretsmanager.Client, input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
-	return svc.GetSecretValue(context.TODO(), input)
+// NovoCotacaoService monta o serviço com a cadeia de provedores de
+// cfg.Provedores.Ordem, que faz as requisições por client e obtém a chave do
+// Fixer de segredos. As cotações são gravadas em repo com o horário de agora.
+func NovoCotacaoService(cfg config.Config, client HTTPClient, repo repository.CotacaoRepository, segredos SecretSource, agora func() time.Time) (*CotacaoService, error) {
+	provider, err := NovoFailoverProvider(cfg.Provedores.Ordem, cfg.Provedores, client, segredos, agora)
+	if err != nil {
+		return nil, err
+	}
+	return &CotacaoService{
+		cfg:      cfg,
+		repo:     repo,
+		provider: provider,
+		segredos: segredos,
+		agora:    agora,
+		cache:    novoCacheCotacoes(time.Duration(cfg.Cotacao.Validade)),
+	}, nil
 }
 
-var JSONUnmarshalFn = json.Unmarshal
-
-var SecretsFetcher = BuscarAPIKeyDoFixer
-var SaveCotacao = SalvarCotacaoNoDynamo
+// Config retorna a configuração com que o serviço foi criado.
+func (s *CotacaoService) Config() config.Config {
+	return s.cfg
+}
 
-func BuscarUltimaCotacao() models.Cotacao {
-	token := SecretsFetcher()
+// MoedasPermitidas retorna a lista de moedas aceitas pela API
+// (Config.Moedas.Permitidas).
+func (s *CotacaoService) MoedasPermitidas() []string {
+	return s.cfg.Moedas.Permitidas
+}
 
-	if token == "" {
-		return BuscarUltimaCotacaoMock()
+// NormalizarMoedas converte uma lista separada por vírgula ("usd, eur") em
+// códigos em maiúsculas, sem repetições e sem itens vazios.
+func NormalizarMoedas(lista string) []string {
+	var moedas []string
+	vistas := map[string]bool{}
+	for _, m := range strings.Split(lista, ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "" || vistas[m] {
+			continue
+		}
+		vistas[m] = true
+		moedas = append(moedas, m)
 	}
+	return moedas
+}
 
-	url := "https://api.apilayer.com/fixer/latest?base=BRL&symbols=USD"
-
-	req, err := NewHTTPRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Erro ao criar requisição:", err)
-		return BuscarUltimaCotacaoMock()
+// ValidarMoedas verifica se origem e destinos estão na lista de moedas
+// permitidas e se nenhum destino é a própria origem.
+func (s *CotacaoService) ValidarMoedas(origem string, destinos []string) error {
+	if err := s.validarPermitidas(origem, destinos); err != nil {
+		return err
+	}
+	for _, destino := range destinos {
+		if destino == origem {
+			return fmt.Errorf("moeda de destino igual à de origem: %q", destino)
+		}
 	}
+	return nil
+}
 
-	req.Header.Add("apikey", token)
+// ValidarConversao verifica se de e para estão na lista de moedas permitidas.
+// Ao contrário de ValidarMoedas, aceita converter uma moeda nela mesma.
+func (s *CotacaoService) ValidarConversao(de, para string) error {
+	return s.validarPermitidas(de, []string{para})
+}
 
-	resp, err := HTTPClientDo(req)
-	if err != nil {
-		fmt.Println("Erro ao buscar cotação:", err)
-		return BuscarUltimaCotacaoMock()
+func (s *CotacaoService) validarPermitidas(origem string, destinos []string) error {
+	permitidas := map[string]bool{}
+	for _, m := range s.MoedasPermitidas() {
+		permitidas[m] = true
 	}
-	defer resp.Body.Close()
 
-	var apiResp apiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		fmt.Println("Erro ao decodificar JSON:", err)
-		return BuscarUltimaCotacaoMock()
+	if !permitidas[origem] {
+		return fmt.Errorf("moeda de origem não permitida: %q", origem)
 	}
-
-	if !apiResp.Success {
-		fmt.Println("API retornou sucesso=false")
-		return BuscarUltimaCotacaoMock()
+	if len(destinos) == 0 {
+		return fmt.Errorf("nenhuma moeda de destino informada")
 	}
-
-	usdRate := apiResp.Rates["USD"]
-	base := apiResp.Base
-
-	cotacao := models.Cotacao{
-		MoedaOrigem:  base,
-		MoedaDestino: "USD",
-		Valor:        usdRate,
-		DataHora:     time.Now(),
+	for _, destino := range destinos {
+		if !permitidas[destino] {
+			return fmt.Errorf("moeda de destino não permitida: %q", destino)
+		}
 	}
-
-	SaveCotacao(cotacao)
-
-	return cotacao
-
+	return nil
 }
 
-func BuscarHistorico(inicio, fim time.Time) []models.Cotacao {
+// DestinosPadrao retorna as moedas permitidas diferentes de origem, usadas
+// quando a atualização não informa destinos.
+func (s *CotacaoService) DestinosPadrao(origem string) []string {
+	var destinos []string
+	for _, moeda := range s.cfg.Moedas.Permitidas {
+		if moeda != origem {
+			destinos = append(destinos, moeda)
+		}
+	}
+	return destinos
+}
 
-	cfg := carregarConfigAWS()
+// UltimasCotacoes retorna a cotação mais recente já gravada de origem para
+// cada moeda de destino, sem consultar os provedores. Como a atualização só
+// grava os pares da moeda pivô, os demais são calculados como em Converter,
+// pela inversa ou pela taxa cruzada, com Config.Cotacao.CasasDecimais.
+// Cotações que nenhuma atualização obteve nas últimas
+// Config.Cotacao.IdadeMaxima vêm marcadas como desatualizadas (veja
+// obtidaEm). Se algum par não tiver cotação gravada nem puder ser calculado,
+// retorna ErrNaoEncontrado.
+func (s *CotacaoService) UltimasCotacoes(ctx context.Context, origem string, destinos []string) ([]models.Cotacao, error) {
+	idadeMaxima := time.Duration(s.cfg.Cotacao.IdadeMaxima)
+	agora := s.agora()
+
+	cotacoes := make([]models.Cotacao, 0, len(destinos))
+	for _, destino := range destinos {
+		cotacao, err := s.cotacaoCalculada(ctx, origem, destino, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("cotação salva de %s para %s: %w", origem, destino, err)
+		}
+
+		cotacao.Valor = cotacao.Valor.Round(int32(s.cfg.Cotacao.CasasDecimais))
+		cotacao.Fonte = models.FonteArmazenamento
+		cotacao.Desatualizada = idadeMaxima > 0 && agora.Sub(obtidaEm(cotacao)) > idadeMaxima
+		cotacoes = append(cotacoes, cotacao)
+	}
+	return cotacoes, nil
+}
 
-	client := dynamodb.NewFromConfig(cfg)
+// obtidaEm retorna quando a cotação foi obtida de um provedor pela última vez.
+// DataHora é o horário das taxas segundo o provedor e não avança enquanto elas
+// não mudam (à noite, no fim de semana), mas cada atualização que recebe as
+// mesmas taxas grava de novo ObtidaEm. Cotações gravadas antes de ObtidaEm
+// existir usam DataHora.
+func obtidaEm(cotacao models.Cotacao) time.Time {
+	if cotacao.ObtidaEm != nil {
+		return *cotacao.ObtidaEm
+	}
+	return cotacao.DataHora
+}
 
-	// Convertendo datas para strings ISO
-	dataInicio := inicio.Format(time.RFC3339)
-	dataFim := fim.Format(time.RFC3339)
+// AtualizarCotacoes busca, em uma única consulta à cadeia de provedores, a
+// cotação de origem para cada moeda de destino e salva cada par como uma
+// cotação própria. Se nenhum provedor responder, retorna
+// ErrUpstreamIndisponivel sem gravar nada; se algum falhar por configuração
+// (como a leitura da chave do Fixer), o erro é ErrConfiguracao.
+//
+// Cada cotação é gravada com o horário informado pelo provedor (ou o horário
+// atual, se ele não informar); gravar de novo o mesmo horário não tem efeito.
+// O provedor, os horários e se ele era de contingência vão junto com a
+// cotação.
+//
+// Cotações obtidas há menos de Config.Cotacao.Validade são devolvidas da
+// memória, sem consultar os provedores nem gravar de novo, e chamadas
+// simultâneas pelas mesmas moedas compartilham uma única consulta.
+//
+// A consulta compartilhada termina no prazo de ctx ou de
+// Config.Prazos.Atualizacao, o que vencer antes, mas não é cancelada quando a
+// chamada que a iniciou desiste, já que outras podem estar à espera dela; a
+// chamada cancelada retorna na hora com o erro de ctx.
+func (s *CotacaoService) AtualizarCotacoes(ctx context.Context, origem string, destinos []string) ([]models.Cotacao, error) {
+	if cotacoes, ok := s.cache.buscar(origem, destinos, s.agora()); ok {
+		metricas.Cache.WithLabelValues("acerto").Inc()
+		return cotacoes, nil
+	}
+	if s.cache.validade > 0 {
+		metricas.Cache.WithLabelValues("falta").Inc()
+	}
 
-	// Filtro em data_hora
-	filtro := expression.Name("data_hora").Between(expression.Value(dataInicio), expression.Value(dataFim))
+	canal := s.cache.grupo.DoChan(origem+">"+strings.Join(destinos, ","), func() (any, error) {
+		// Outra chamada pode ter preenchido o cache enquanto esta esperava
+		if cotacoes, ok := s.cache.buscar(origem, destinos, s.agora()); ok {
+			return cotacoes, nil
+		}
+
+		compartilhado, cancelar := comPrazo(context.WithoutCancel(ctx), s.cfg.Prazos.Atualizacao)
+		defer cancelar()
+		if prazo, ok := ctx.Deadline(); ok {
+			var cancelarPrazo context.CancelFunc
+			compartilhado, cancelarPrazo = context.WithDeadline(compartilhado, prazo)
+			defer cancelarPrazo()
+		}
+		return s.buscarNosProvedores(compartilhado, origem, destinos)
+	})
 
-	expr, err := expression.NewBuilder().WithFilter(filtro).Build()
-	if err != nil {
-		fmt.Println("Erro ao construir expressão:", err)
-		return nil
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("atualização de %s interrompida: %w", origem, ctx.Err())
+	case resultado := <-canal:
+		if resultado.Err != nil {
+			return nil, resultado.Err
+		}
+		return slices.Clone(resultado.Val.([]models.Cotacao)), nil
 	}
+}
 
-	// Scan com filtro - Tipo JPA Specifications
-	input := &dynamodb.ScanInput{
-		TableName:                 aws.String("Cotacoes"),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
+func (s *CotacaoService) buscarNosProvedores(ctx context.Context, origem string, destinos []string) ([]models.Cotacao, error) {
+	taxas, err := s.provider.BuscarTaxas(ctx, origem, destinos)
+	if errors.Is(err, ErrConfiguracao) {
+		return nil, err
 	}
-
-	result, err := DynamoScan(client, input)
 	if err != nil {
-		fmt.Println("Erro ao fazer scan no DynamoDB:", err)
-		return nil
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamIndisponivel, err)
 	}
 
-	var cotacoes []models.Cotacao
-	if err := UnmarshalList(result.Items, &cotacoes); err != nil {
-		fmt.Println("Erro ao converter resultados:", err)
-		return nil
+	// Cada cotação é identificada pelo horário que o provedor informa para a
+	// taxa dela, de modo que buscar de novo as mesmas taxas não cria outra
+	// cotação, e uma moeda atrasada não impede gravar a taxa nova das outras
+	agora := s.agora()
+	cotacoes := make([]models.Cotacao, 0, len(destinos))
+	for _, destino := range destinos {
+		dataHora := taxas.DataHoraDe(destino)
+		var dataHoraProvedor *time.Time
+		if dataHora.IsZero() {
+			dataHora = agora
+		} else {
+			dataHoraProvedor = &dataHora
+		}
+
+		cotacoes = append(cotacoes, models.Cotacao{
+			MoedaOrigem:      taxas.Base,
+			MoedaDestino:     destino,
+			Valor:            taxas.Rates[destino].Round(int32(s.cfg.Cotacao.CasasDecimais)),
+			DataHora:         dataHora,
+			Provedor:         taxas.Provedor,
+			DataHoraProvedor: dataHoraProvedor,
+			ObtidaEm:         &agora,
+			Contingencia:     taxas.Contingencia,
+			Fonte:            taxas.Provedor,
+		})
 	}
 
-	return cotacoes
-}
-
-func SalvarCotacaoNoDynamo(cotacao models.Cotacao) {
-
-	cfg := carregarConfigAWS()
-
-	client := dynamodb.NewFromConfig(cfg)
-
-	item, err := attributevalue.MarshalMap(cotacao)
-	if err != nil {
-		fmt.Println("Erro ao converter cotação para DynamoDB:", err)
-		return
+	for _, cotacao := range cotacoes {
+		if err := s.SalvarCotacao(ctx, cotacao); err != nil {
+			return nil, err
+		}
 	}
 
-	_, err = PutItemFn(client, &dynamodb.PutItemInput{
-		TableName: aws.String("Cotacoes"),
-		Item:      item,
-	})
+	s.cache.guardar(cotacoes, agora)
+	return cotacoes, nil
+}
 
+// BuscarUltimaCotacaoSalva retorna a cotação mais recente de origem para
+// destino já gravada, ou ErrNaoEncontrado se não houver nenhuma.
+func (s *CotacaoService) BuscarUltimaCotacaoSalva(ctx context.Context, origem, destino string) (models.Cotacao, error) {
+	ctx, concluir := s.operacao(ctx, "Latest")
+	cotacao, err := s.repo.Latest(ctx, origem, destino)
+	concluir(err)
 	if err != nil {
-		fmt.Println("Erro ao salvar no DynamoDB:", err)
-	} else {
-		fmt.Println("Cotação salva no DynamoDB com sucesso!")
+		return models.Cotacao{}, erroArmazenamento(err)
 	}
+	return cotacao, nil
 }
 
-func BuscarAPIKeyDoFixer() string {
-	secretName := "fixer-api-key-dev"
-
-	cfg := carregarConfigAWS()
-
-	svc := secretsmanager.NewFromConfig(cfg)
-
-	result, err := GetSecretValueFn(svc, &secretsmanager.GetSecretValueInput{
-		SecretId: &secretName,
-	})
-
+// BuscarHistorico retorna as cotações de origem para destino gravadas entre
+// inicio e fim.
+func (s *CotacaoService) BuscarHistorico(ctx context.Context, origem, destino string, inicio, fim time.Time) ([]models.Cotacao, error) {
+	ctx, concluir := s.operacao(ctx, "Range")
+	cotacoes, err := s.repo.Range(ctx, origem, destino, inicio, fim)
+	concluir(err)
 	if err != nil {
-		fmt.Println("Erro ao obter segredo:", err)
-		return ""
+		return nil, erroArmazenamento(err)
 	}
+	return cotacoes, nil
+}
 
-	var parsed map[string]string
-	if err := JSONUnmarshalFn([]byte(*result.SecretString), &parsed); err != nil {
-		fmt.Println("Erro ao interpretar JSON do segredo:", err)
-		return ""
+// BuscarHistoricoPaginado retorna uma página de até limite cotações de origem
+// para destino entre inicio e fim, continuando a partir de cursor (vazio na
+// primeira página). O limite vai de 1 a Config.Historico.LimiteMaximo.
+func (s *CotacaoService) BuscarHistoricoPaginado(ctx context.Context, origem, destino string, inicio, fim time.Time, limite int, cursor string) (models.PaginaCotacoes, error) {
+	if limite <= 0 || limite > s.cfg.Historico.LimiteMaximo {
+		return models.PaginaCotacoes{}, fmt.Errorf("limite deve estar entre 1 e %d: %d", s.cfg.Historico.LimiteMaximo, limite)
 	}
 
-	return parsed["fixer_api_key"]
+	ctx, concluir := s.operacao(ctx, "RangePage")
+	pagina, err := s.repo.RangePage(ctx, origem, destino, inicio, fim, limite, cursor)
+	concluir(err)
+	if err != nil {
+		return models.PaginaCotacoes{}, erroArmazenamento(err)
+	}
+	return pagina, nil
 }
 
-func carregarConfigAWS() aws.Config {
-	region := "us-east-1"
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+// SalvarCotacao grava a cotação no armazenamento do serviço.
+func (s *CotacaoService) SalvarCotacao(ctx context.Context, cotacao models.Cotacao) error {
+	ctx, concluir := s.operacao(ctx, "Save")
+	err := s.repo.Save(ctx, cotacao)
+	concluir(err)
 	if err != nil {
-		fmt.Println("Erro ao carregar configuração da AWS:", err)
-		os.Exit(1) // encerra a aplicação
+		return erroArmazenamento(err)
 	}
-	return cfg
+	return nil
 }
 
-func BuscarUltimaCotacaoMock() models.Cotacao {
-	return models.Cotacao{
-		MoedaOrigem:  "BRL",
-		MoedaDestino: "USD",
-		Valor:        5.00,
-		DataHora:     time.Now(),
+// operacao prepara a operação op no armazenamento: aplica a ctx o prazo de
+// Config.Prazos.Armazenamento e devolve a função que a encerra com o erro
+// obtido, liberando o prazo e registrando duração e resultado nas métricas e
+// no span "armazenamento <op>", pai das chamadas ao banco.
+func (s *CotacaoService) operacao(ctx context.Context, op string) (context.Context, func(error)) {
+	inicio := time.Now()
+	ctx, span := rastreamento.Iniciar(ctx, "armazenamento "+op, trace.WithAttributes(
+		attribute.String("armazenamento.backend", s.cfg.Armazenamento.Backend),
+		attribute.String("armazenamento.operacao", op),
+	))
+	ctx, cancelar := comPrazo(ctx, s.cfg.Prazos.Armazenamento)
+	return ctx, func(err error) {
+		cancelar()
+		resultado := metricas.Resultado(err)
+		if errors.Is(err, ErrNaoEncontrado) {
+			resultado = "nao_encontrado"
+			err = nil
+		}
+		metricas.Armazenamento.WithLabelValues(s.cfg.Armazenamento.Backend, op, resultado).Observe(metricas.Desde(inicio))
+		span.SetAttributes(attribute.String("resultado", resultado))
+		rastreamento.Finalizar(span, err)
 	}
+}
 
+// comPrazo deriva de ctx um contexto que vence em prazo.
+func comPrazo(ctx context.Context, prazo config.Duracao) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(prazo))
 }
@@ -1,255 +1,245 @@
 package services_test
 
 import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
 	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
 	"cambio-brl-usd/services"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func TestBuscarUltimaCotacao(t *testing.T) {
-	cotacao := services.BuscarUltimaCotacao()
-	assert.Equal(t, "BRL", cotacao.MoedaOrigem)
-	assert.Equal(t, "USD", cotacao.MoedaDestino)
-	assert.Greater(t, cotacao.Valor, 0.0)
+// ctx é o contexto das chamadas ao serviço e aos provedores nos testes.
+var ctx = context.Background()
+
+// dependencias são as peças com que novoService monta o serviço.
+type dependencias struct {
+	cfg      config.Config
+	client   services.HTTPClient
+	repo     repository.CotacaoRepository
+	segredos services.SecretSource
+	agora    func() time.Time
 }
 
-func TestBuscarUltimaCotacao_FallbackPorTokenVazio(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string { return "" }
-	defer func() { services.SecretsFetcher = original }()
+// novoService monta o serviço do teste. Por padrão usa só o Fixer, em um
+// endereço que nunca responde, para não depender de APIs externas, grava em
+// memória e obtém do Fixer a chave "token". As novas tentativas aos provedores
+// não esperam. ajustar, se não for nil, altera essas dependências antes.
+func novoService(t *testing.T, ajustar func(*dependencias)) *services.CotacaoService {
+	d := dependencias{
+		cfg:      config.Padrao(),
+		client:   http.DefaultClient,
+		repo:     repository.NovoMemoryRepository(),
+		segredos: services.SecretSourceFunc(func(context.Context) (string, error) { return "token", nil }),
+		agora:    time.Now,
+	}
+	d.cfg.Provedores.Ordem = []string{"fixer"}
+	d.cfg.Provedores.FixerURL = "http://fixer.invalid"
+	d.cfg.Provedores.EsperaInicial = config.Duracao(time.Millisecond)
+	d.cfg.Provedores.EsperaMaxima = config.Duracao(time.Millisecond)
+	if ajustar != nil {
+		ajustar(&d)
+	}
+
+	svc, err := services.NovoCotacaoService(d.cfg, d.client, d.repo, d.segredos, d.agora)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback, recebeu: %f", cotacao.Valor)
-	}
+// usarExchangeRateHost faz a cadeia consultar só um servidor local que
+// responde com body.
+func (d *dependencias) usarExchangeRateHost(t *testing.T, body string) {
+	d.cfg.Provedores.Ordem = []string{"exchangeratehost"}
+	d.cfg.Provedores.ExchangeRateURL = servidor(t, body).URL
 }
 
-func TestBuscarUltimaCotacao_ErroAoCriarRequisicao(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string { return "token" }
-	defer func() { services.SecretsFetcher = original }()
+// segredoAusente é uma fonte em que a busca da chave do Fixer falha.
+var segredoAusente = services.SecretSourceFunc(func(context.Context) (string, error) {
+	return "", errors.New("segredo ausente")
+})
 
-	os.Setenv("FIXER_API_URL", ":")
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback, recebeu: %f", cotacao.Valor)
-	}
-}
+// clientFunc adapta uma função a services.HTTPClient.
+type clientFunc func(*http.Request) (*http.Response, error)
 
-func TestBuscarHistorico_ErroNaExpressao(t *testing.T) {
-	inicio := time.Now().Add(-48 * time.Hour)
-	fim := time.Now().Add(-48 * time.Hour)
-	_ = services.BuscarHistorico(inicio, fim)
-}
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
 
-func TestSalvarCotacaoNoDynamo_ExecutaSemPanic(t *testing.T) {
-	cotacao := services.BuscarUltimaCotacaoMock()
-	defer func() {
-		if r := recover(); r != nil {
-			t.Errorf("SalvarCotacaoNoDynamo causou panic: %v", r)
-		}
-	}()
-	services.SalvarCotacaoNoDynamo(cotacao)
+// servidor responde a toda requisição com body.
+func servidor(t *testing.T, body string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
 }
 
-func TestBuscarAPIKeyDoFixer_SegredoNaoExiste(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string {
-		return ""
-	}
-	defer func() { services.SecretsFetcher = original }()
+// repositorioComFalha é um armazenamento em que toda operação falha.
+type repositorioComFalha struct{}
 
-	token := services.SecretsFetcher()
-	if token != "" {
-		t.Errorf("Esperava segredo vazio, obteve: %s", token)
-	}
+func (repositorioComFalha) Save(context.Context, models.Cotacao) error {
+	return errors.New("erro simulado")
 }
 
-func TestBuscarAPIKeyDoFixer_JSONInvalido(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string {
-		raw := `{"fixer_api_key":123}`
-		var parsed map[string]string
-		err := json.Unmarshal([]byte(raw), &parsed)
-		if err != nil {
-			return ""
-		}
-		return parsed["fixer_api_key"]
-	}
-	defer func() { services.SecretsFetcher = original }()
+func (repositorioComFalha) Latest(context.Context, string, string) (models.Cotacao, error) {
+	return models.Cotacao{}, errors.New("erro simulado")
+}
 
-	token := services.SecretsFetcher()
-	if token != "" {
-		t.Errorf("Esperava erro de JSON e segredo vazio, obteve: %s", token)
-	}
+func (repositorioComFalha) Closest(context.Context, string, string, time.Time) (models.Cotacao, error) {
+	return models.Cotacao{}, errors.New("erro simulado")
 }
 
-func TestBuscarHistorico_ErroNoScan(t *testing.T) {
-	inicio := time.Now().Add(-24 * time.Hour)
-	fim := time.Now()
-	cotacoes := services.BuscarHistorico(inicio, fim)
-	_ = cotacoes
+func (repositorioComFalha) Range(context.Context, string, string, time.Time, time.Time) ([]models.Cotacao, error) {
+	return nil, errors.New("erro simulado")
 }
 
-func TestBuscarHistorico_ErroNoUnmarshal(t *testing.T) {
-	inicio := time.Now().Add(-24 * time.Hour)
-	fim := time.Now()
-	_ = services.BuscarHistorico(inicio, fim)
+func (repositorioComFalha) RangePage(context.Context, string, string, time.Time, time.Time, int, string) (models.PaginaCotacoes, error) {
+	return models.PaginaCotacoes{}, errors.New("erro simulado")
 }
 
-func TestBuscarUltimaCotacao_ErroAoCriarRequest(t *testing.T) {
-	original := services.NewHTTPRequest
-	services.NewHTTPRequest = func(method string, url string, body io.Reader) (*http.Request, error) {
-		return nil, fmt.Errorf("erro simulado")
-	}
-	defer func() { services.NewHTTPRequest = original }()
+func (repositorioComFalha) Delete(context.Context, models.Cotacao) error {
+	return errors.New("erro simulado")
+}
+
+func (repositorioComFalha) Verificar(context.Context) error {
+	return errors.New("erro simulado")
+}
 
-	services.SecretsFetcher = func() string { return "fake" }
+func TestNovoCotacaoService_ProvedorDesconhecido(t *testing.T) {
+	cfg := config.Padrao()
+	cfg.Provedores.Ordem = []string{"inexistente"}
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("esperava fallback")
-	}
+	_, err := services.NovoCotacaoService(cfg, http.DefaultClient, repository.NovoMemoryRepository(), nil, time.Now)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
 }
 
-func TestBuscarUltimaCotacao_ErroClientDo1(t *testing.T) {
-	original := services.HTTPClientDo
-	services.HTTPClientDo = func(_ *http.Request) (*http.Response, error) {
-		return nil, fmt.Errorf("erro simulado no Do")
-	}
-	defer func() { services.HTTPClientDo = original }()
+func TestAtualizarCotacoes(t *testing.T) {
+	srv := servidor(t, `{"success":true,"base":"BRL","rates":{"USD":0.18}}`)
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = srv.URL })
 
-	services.SecretsFetcher = func() string { return "fake" }
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("esperava fallback")
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 1) {
+		assert.Equal(t, "BRL", cotacoes[0].MoedaOrigem)
+		assert.Equal(t, "USD", cotacoes[0].MoedaDestino)
+		assert.True(t, cotacoes[0].Valor.IsPositive())
 	}
 }
 
-func TestBuscarUltimaCotacao_ErroDecodeJSON(t *testing.T) {
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.Write([]byte("INVALID JSON"))
-	}))
-	defer srv.Close()
+func TestAtualizarCotacoes_UsaRelogioDoServico(t *testing.T) {
+	srv := servidor(t, `{"success":true,"base":"BRL","rates":{"USD":0.18}}`)
+	agora := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.agora = func() time.Time { return agora }
+	})
 
-	services.SecretsFetcher = func() string { return "fake" }
-	os.Setenv("FIXER_API_URL", srv.URL)
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("esperava fallback")
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 1) {
+		assert.Equal(t, agora, cotacoes[0].DataHora)
 	}
 }
 
-func TestBuscarHistorico_ErroExpressao(t *testing.T) {
-	original := services.DynamoScan
-	services.DynamoScan = func(_ *dynamodb.Client, _ *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-		return nil, fmt.Errorf("erro fake scan")
-	}
-	defer func() { services.DynamoScan = original }()
+func TestAtualizarCotacoes_ErroPorTokenVazio(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.segredos = segredoAusente })
 
-	cotacoes := services.BuscarHistorico(time.Now(), time.Now())
-	if cotacoes != nil {
-		t.Errorf("esperava nil em erro de Scan")
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	if err == nil {
+		t.Errorf("Esperava erro")
 	}
 }
 
-func TestBuscarUltimaCotacao_TokenVazio(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string { return "" }
-	defer func() { services.SecretsFetcher = original }()
+func TestAtualizarCotacoes_ErroAoCriarRequisicao(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = ":" })
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback, recebeu: %f", cotacao.Valor)
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	if err == nil {
+		t.Errorf("Esperava erro")
 	}
 }
 
-func TestBuscarUltimaCotacao_ErroCriarRequisicao(t *testing.T) {
-	original := services.NewHTTPRequest
-	services.NewHTTPRequest = func(method string, url string, body io.Reader) (*http.Request, error) {
-		return nil, errors.New("erro simulado")
+func TestSalvarCotacao_ExecutaSemPanic(t *testing.T) {
+	cotacao := models.Cotacao{
+		MoedaOrigem:  "BRL",
+		MoedaDestino: "USD",
+		Valor:        decimal.RequireFromString("5.00"),
+		DataHora:     time.Now(),
 	}
-	defer func() { services.NewHTTPRequest = original }()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("SalvarCotacao causou panic: %v", r)
+		}
+	}()
+	_ = novoService(t, nil).SalvarCotacao(ctx, cotacao)
+}
 
-	services.SecretsFetcher = func() string { return "token" }
+func TestAtualizarCotacoes_ErroClientDo(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.client = clientFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("erro client.Do simulado")
+		})
+	})
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback por erro ao criar request")
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	if err == nil {
+		t.Errorf("Esperava erro no client.Do")
 	}
 }
 
-func TestBuscarUltimaCotacao_ErroClientDo(t *testing.T) {
-	original := services.HTTPClientDo
-	services.HTTPClientDo = func(_ *http.Request) (*http.Response, error) {
-		return nil, errors.New("erro client.Do simulado")
-	}
-	defer func() { services.HTTPClientDo = original }()
-
-	services.SecretsFetcher = func() string { return "token" }
-	services.NewHTTPRequest = http.NewRequest
+func TestAtualizarCotacoes_ErroDecodeJSON(t *testing.T) {
+	srv := servidor(t, "INVALID JSON")
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = srv.URL })
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback por erro no client.Do")
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	if err == nil {
+		t.Errorf("esperava erro")
 	}
 }
 
-func TestBuscarUltimaCotacao_JSONInvalido(t *testing.T) {
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.Write([]byte("nao-e-json"))
-	}))
-	defer srv.Close()
-
-	services.SecretsFetcher = func() string { return "token" }
-	saved := services.NewHTTPRequest
-	services.NewHTTPRequest = func(method, url string, body io.Reader) (*http.Request, error) {
-		return http.NewRequest(method, srv.URL, body)
-	}
-	defer func() { services.NewHTTPRequest = saved }()
+func TestBuscarHistorico_ErroExpressao(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback por JSON inválido")
+	cotacoes, err := svc.BuscarHistorico(ctx, "BRL", "USD", time.Now(), time.Now())
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+	if cotacoes != nil {
+		t.Errorf("esperava nil em erro de Scan")
 	}
 }
 
-func TestBuscarUltimaCotacao_SuccessFalse(t *testing.T) {
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.Write([]byte(`{"base":"BRL","success":false,"rates":{"USD":5.0}}`))
-	}))
-	defer srv.Close()
-
-	services.SecretsFetcher = func() string { return "token" }
-	saved := services.NewHTTPRequest
-	services.NewHTTPRequest = func(method, url string, body io.Reader) (*http.Request, error) {
-		return http.NewRequest(method, srv.URL, body)
-	}
-	defer func() { services.NewHTTPRequest = saved }()
+func TestAtualizarCotacoes_SuccessFalse(t *testing.T) {
+	srv := servidor(t, `{"base":"BRL","success":false,"rates":{"USD":5.0}}`)
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = srv.URL })
 
-	cotacao := services.BuscarUltimaCotacao()
-	if cotacao.Valor != 5.00 {
-		t.Errorf("Esperava fallback por success=false")
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	if err == nil {
+		t.Errorf("Esperava erro por success=false")
 	}
 }
 
-func TestBuscarUltimaCotacao_ComSucesso(t *testing.T) {
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+func TestAtualizarCotacoes_ComSucesso(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("apikey"))
 		w.Write([]byte(`{
 			"success": true,
 			"base": "BRL",
@@ -258,172 +248,592 @@ func TestBuscarUltimaCotacao_ComSucesso(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	services.SecretsFetcher = func() string { return "token" }
-
-	savedRequest := services.NewHTTPRequest
-	services.NewHTTPRequest = func(method, url string, body io.Reader) (*http.Request, error) {
-		return http.NewRequest(method, srv.URL, body)
-	}
-	defer func() { services.NewHTTPRequest = savedRequest }()
-
-	savedSaver := services.SaveCotacao
-	services.SaveCotacao = func(c models.Cotacao) {
-		t.Logf("Cotação salva mockada: %+v", c)
-	}
-	defer func() { services.SaveCotacao = savedSaver }()
-
-	cotacao := services.BuscarUltimaCotacao()
-
-	if cotacao.Valor != 5.42 {
-		t.Errorf("Esperava valor 5.42, recebeu: %f", cotacao.Valor)
-	}
-	if cotacao.MoedaOrigem != "BRL" || cotacao.MoedaDestino != "USD" {
-		t.Errorf("Esperava moedas BRL→USD, recebeu: %+v", cotacao)
-	}
-}
-
-func TestBuscarHistorico_ErroNaExpressaoOther(t *testing.T) {
-	defer func() {
-		if r := recover(); r != nil {
-			t.Errorf("Função não deve causar panic mesmo com erro na expressão")
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.repo = repo
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 1) {
+		cotacao := cotacoes[0]
+		assert.Equal(t, "fixer", cotacao.Fonte)
+		assert.False(t, cotacao.Desatualizada)
+		if !cotacao.Valor.Equal(decimal.RequireFromString("5.42")) {
+			t.Errorf("Esperava valor 5.42, recebeu: %s", cotacao.Valor)
 		}
-	}()
-	fakeInicio := time.Now()
-	fakeFim := time.Now()
+		if cotacao.MoedaOrigem != "BRL" || cotacao.MoedaDestino != "USD" {
+			t.Errorf("Esperava moedas BRL→USD, recebeu: %+v", cotacao)
+		}
+	}
 
-	_ = services.BuscarHistorico(fakeInicio, fakeFim)
+	salva, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "5.42", salva.Valor.String())
 }
 
 func TestBuscarHistorico_ErroScanDynamo(t *testing.T) {
-	original := services.DynamoScan
-	services.DynamoScan = func(_ *dynamodb.Client, _ *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-		return nil, errors.New("simulando erro no scan")
-	}
-	defer func() { services.DynamoScan = original }()
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
 
 	fakeInicio := time.Now().Add(-24 * time.Hour)
 	fakeFim := time.Now()
 
-	result := services.BuscarHistorico(fakeInicio, fakeFim)
+	result, err := svc.BuscarHistorico(ctx, "BRL", "USD", fakeInicio, fakeFim)
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
 	if result != nil {
 		t.Errorf("Esperava retorno nil em erro de scan")
 	}
 }
 
-func TestBuscarHistorico_ErroUnmarshal(t *testing.T) {
-	original := services.UnmarshalList
-	services.UnmarshalList = func(_ []map[string]types.AttributeValue, _ interface{}) error {
-		return errors.New("erro simulado no unmarshal")
+func TestSalvarCotacao_Sucesso(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) { d.repo = repo })
+
+	cotacao := models.Cotacao{
+		MoedaOrigem:  "BRL",
+		MoedaDestino: "USD",
+		Valor:        decimal.RequireFromString("5.42"),
+		DataHora:     time.Now(),
 	}
-	defer func() { services.UnmarshalList = original }()
 
-	fakeInicio := time.Now().Add(-24 * time.Hour)
-	fakeFim := time.Now()
+	err := svc.SalvarCotacao(ctx, cotacao)
 
-	result := services.BuscarHistorico(fakeInicio, fakeFim)
-	if result != nil {
-		t.Errorf("Esperava retorno nil em erro de unmarshal")
-	}
+	assert.NoError(t, err)
+	salva, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "5.42", salva.Valor.String())
 }
 
-func TestSalvarCotacaoNoDynamo_Sucesso(t *testing.T) {
-	called := false
-
-	original := services.PutItemFn
-	services.PutItemFn = func(_ *dynamodb.Client, _ *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
-		called = true
-		return &dynamodb.PutItemOutput{}, nil
-	}
-	defer func() { services.PutItemFn = original }()
+func TestSalvarCotacao_ErroAoGravar(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
 
 	cotacao := models.Cotacao{
 		MoedaOrigem:  "BRL",
 		MoedaDestino: "USD",
-		Valor:        5.42,
+		Valor:        decimal.RequireFromString("5.00"),
 		DataHora:     time.Now(),
 	}
 
-	services.SaveCotacao(cotacao)
+	err := svc.SalvarCotacao(ctx, cotacao)
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
 
-	if !called {
-		t.Errorf("PutItemFn não foi chamado")
-	}
+func TestAtualizarCotacoes_VariosDestinosEmUmaChamada(t *testing.T) {
+	chamadas := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chamadas++
+		assert.Equal(t, "BRL", r.URL.Query().Get("base"))
+		assert.Equal(t, "USD,EUR,JPY", r.URL.Query().Get("symbols"))
+		w.Write([]byte(`{"success":true,"base":"BRL","rates":{"USD":0.17,"EUR":0.16,"JPY":25.1}}`))
+	}))
+	defer srv.Close()
+
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.repo = repo
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "EUR", "JPY"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, chamadas)
+	assert.Len(t, cotacoes, 3)
+	for _, cotacao := range cotacoes {
+		salva, err := repo.Latest(ctx, "BRL", cotacao.MoedaDestino)
+		assert.NoError(t, err)
+		assert.Equal(t, cotacao.Valor, salva.Valor)
+	}
+	assert.Equal(t, "EUR", cotacoes[1].MoedaDestino)
+	assert.Equal(t, "0.16", cotacoes[1].Valor.String())
+	assert.Equal(t, "25.1", cotacoes[2].Valor.String())
+}
+
+func TestAtualizarCotacoes_MoedaAusenteNaResposta(t *testing.T) {
+	srv := servidor(t, `{"success":true,"base":"BRL","rates":{"USD":0.17}}`)
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = srv.URL })
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "GBP"})
+	assert.ErrorContains(t, err, "GBP")
+}
+
+func TestNormalizarMoedas(t *testing.T) {
+	assert.Equal(t, []string{"USD", "EUR"}, services.NormalizarMoedas(" usd,EUR,,usd "))
+	assert.Nil(t, services.NormalizarMoedas(""))
 }
 
-func TestSalvarCotacaoNoDynamo_ErroPutItem(t *testing.T) {
-	original := services.PutItemFn
-	services.PutItemFn = func(_ *dynamodb.Client, _ *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
-		return nil, errors.New("erro simulado")
+func TestValidarMoedas(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.cfg.Moedas.Permitidas = []string{"BRL", "USD", "EUR"} })
+
+	assert.NoError(t, svc.ValidarMoedas("BRL", []string{"USD", "EUR"}))
+	assert.Error(t, svc.ValidarMoedas("XYZ", []string{"USD"}))
+	assert.Error(t, svc.ValidarMoedas("BRL", []string{"JPY"}))
+	assert.Error(t, svc.ValidarMoedas("BRL", []string{"BRL"}))
+	assert.Error(t, svc.ValidarMoedas("BRL", nil))
+}
+
+func TestMoedasPermitidas_Padrao(t *testing.T) {
+	assert.Equal(t, config.Padrao().Moedas.Permitidas, novoService(t, nil).MoedasPermitidas())
+}
+
+func TestAtualizarCotacoes_FailoverParaSegundoProvedor(t *testing.T) {
+	fixer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fixer.Close()
+	reserva := servidor(t, `{"base":"BRL","rates":{"USD":0.18}}`)
+
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.Ordem = []string{"fixer", "exchangeratehost"}
+		d.cfg.Provedores.FixerURL = fixer.URL
+		d.cfg.Provedores.ExchangeRateURL = reserva.URL
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.18", cotacoes[0].Valor.String())
+	assert.Equal(t, "exchangeratehost", cotacoes[0].Fonte)
+	assert.Equal(t, "exchangeratehost", cotacoes[0].Provedor)
+	assert.True(t, cotacoes[0].Contingencia)
+	assert.False(t, cotacoes[0].Desatualizada)
+}
+
+func TestAtualizarCotacoes_GravaMetadadosDoProvedor(t *testing.T) {
+	srv := servidor(t, `{"success":true,"timestamp":1745236800,"base":"BRL","rates":{"USD":0.18}}`)
+	repo := repository.NovoMemoryRepository()
+	agora := time.Date(2025, 4, 21, 12, 3, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.repo = repo
+		d.agora = func() time.Time { return agora }
+	})
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+
+	salva, err := repo.Latest(ctx, "BRL", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixer", salva.Provedor)
+	assert.False(t, salva.Contingencia)
+	if assert.NotNil(t, salva.DataHoraProvedor) && assert.NotNil(t, salva.ObtidaEm) {
+		assert.Equal(t, time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC), *salva.DataHoraProvedor)
+		assert.Equal(t, agora, *salva.ObtidaEm)
 	}
-	defer func() { services.PutItemFn = original }()
+}
 
-	cotacao := models.Cotacao{
-		MoedaOrigem:  "BRL",
-		MoedaDestino: "USD",
-		Valor:        5.00,
-		DataHora:     time.Now(),
+func TestAtualizarCotacoes_ProvedorSemHorario(t *testing.T) {
+	srv := servidor(t, `{"success":true,"base":"BRL","rates":{"USD":0.18}}`)
+	agora := time.Date(2025, 4, 21, 12, 3, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.agora = func() time.Time { return agora }
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, agora, cotacoes[0].DataHora)
+	assert.Nil(t, cotacoes[0].DataHoraProvedor)
+	assert.Equal(t, &agora, cotacoes[0].ObtidaEm)
+}
+
+func TestUltimasCotacoes_LeCotacaoSalvaSemConsultarProvedores(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	for _, c := range []struct {
+		valor    string
+		dataHora string
+	}{{"5.10", "2025-04-20T12:00:00Z"}, {"5.30", "2025-04-21T12:00:00Z"}, {"5.20", "2025-04-19T12:00:00Z"}} {
+		dataHora, _ := time.Parse(time.RFC3339, c.dataHora)
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString(c.valor), DataHora: dataHora})
+	}
+	agora := time.Date(2025, 4, 21, 13, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repo
+		d.agora = func() time.Time { return agora }
+		d.client = clientFunc(func(*http.Request) (*http.Response, error) {
+			t.Error("não deveria consultar provedores")
+			return nil, errors.New("inesperado")
+		})
+	})
+
+	cotacoes, err := svc.UltimasCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "5.3", cotacoes[0].Valor.String())
+	assert.Equal(t, models.FonteArmazenamento, cotacoes[0].Fonte)
+	assert.False(t, cotacoes[0].Desatualizada)
+
+	// Gravada há mais que a idade máxima padrão, de 13h
+	agora = agora.Add(13 * time.Hour)
+	cotacoes, _ = svc.UltimasCotacoes(ctx, "BRL", []string{"USD"})
+	assert.True(t, cotacoes[0].Desatualizada)
+}
+
+func TestUltimasCotacoes_IdadeContadaDaUltimaAtualizacao(t *testing.T) {
+	// O provedor devolve sempre as mesmas taxas, de sexta-feira
+	srv := servidor(t, `{"success":true,"timestamp":1745020800,"base":"BRL","rates":{"USD":0.18}}`)
+	agora := time.Date(2025, 4, 21, 8, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.agora = func() time.Time { return agora }
+	})
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+	cotacoes, _ := svc.UltimasCotacoes(ctx, "BRL", []string{"USD"})
+	assert.False(t, cotacoes[0].Desatualizada, "as taxas são antigas, mas acabaram de ser obtidas")
+
+	agora = agora.Add(14 * time.Hour)
+	cotacoes, _ = svc.UltimasCotacoes(ctx, "BRL", []string{"USD"})
+	assert.True(t, cotacoes[0].Desatualizada)
+
+	_, err = svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.NoError(t, err)
+	cotacoes, _ = svc.UltimasCotacoes(ctx, "BRL", []string{"USD"})
+	assert.False(t, cotacoes[0].Desatualizada, "a nova atualização recebeu as mesmas taxas")
+	assert.Equal(t, agora, *cotacoes[0].ObtidaEm)
+}
+
+func TestUltimasCotacoes_ParesSemAMoedaPivo(t *testing.T) {
+	obtidaUSD := time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC)
+	obtidaEUR := obtidaUSD.Add(-14 * time.Hour)
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.2"), DataHora: obtidaUSD, ObtidaEm: &obtidaUSD})
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "EUR", Valor: decimal.RequireFromString("0.16"), DataHora: obtidaEUR, ObtidaEm: &obtidaEUR})
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repo
+		d.agora = func() time.Time { return obtidaUSD }
+	})
+
+	cotacoes, err := svc.UltimasCotacoes(ctx, "USD", []string{"BRL", "EUR"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, cotacoes, 2) {
+		// Inversa de BRL → USD
+		assert.Equal(t, "USD", cotacoes[0].MoedaOrigem)
+		assert.Equal(t, "BRL", cotacoes[0].MoedaDestino)
+		assert.Equal(t, "5", cotacoes[0].Valor.String())
+		assert.Empty(t, cotacoes[0].MoedaPivo)
+		assert.False(t, cotacoes[0].Desatualizada)
+
+		// USD → BRL → EUR, com a data e a obtenção da cotação mais antiga
+		assert.Equal(t, "EUR", cotacoes[1].MoedaDestino)
+		assert.Equal(t, "0.8", cotacoes[1].Valor.String())
+		assert.Equal(t, "BRL", cotacoes[1].MoedaPivo)
+		assert.Equal(t, obtidaEUR, cotacoes[1].DataHora)
+		assert.True(t, cotacoes[1].Desatualizada)
 	}
+}
+
+func TestUltimasCotacoes_SemCotacaoSalva(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("5"), DataHora: time.Now()})
+	svc := novoService(t, func(d *dependencias) { d.repo = repo })
+
+	cotacoes, err := svc.UltimasCotacoes(ctx, "BRL", []string{"USD", "EUR"})
 
-	services.SaveCotacao(cotacao)
+	assert.ErrorIs(t, err, services.ErrNaoEncontrado)
+	assert.ErrorContains(t, err, "EUR")
+	assert.Nil(t, cotacoes)
+
+	_, err = novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} }).UltimasCotacoes(ctx, "BRL", []string{"USD"})
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
+
+func TestAtualizarCotacoes_ErroSemProvedorNaoUsaCotacaoSalva(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("5"), DataHora: time.Now()})
+	svc := novoService(t, func(d *dependencias) {
+		d.segredos = segredoAusente
+		d.repo = repo
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.ErrorIs(t, err, services.ErrUpstreamIndisponivel)
+	assert.Nil(t, cotacoes)
+}
+
+func TestAtualizarCotacoes_FalhaDeConfiguracaoNaoEhIndisponibilidade(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.segredos = services.SecretSourceFunc(func(context.Context) (string, error) {
+			return "", fmt.Errorf("%w: erro ao obter segredo", services.ErrConfiguracao)
+		})
+	})
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+	assert.NotErrorIs(t, err, services.ErrUpstreamIndisponivel)
 }
 
-func TestBuscarAPIKeyDoFixer_ErroSecretsManager(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string {
-		t.Log("Simulando erro ao obter segredo")
-		return ""
+func TestAtualizarCotacoes_MesmaTaxaDoProvedorNaoDuplica(t *testing.T) {
+	srv := servidor(t, `{"success":true,"timestamp":1745236800,"base":"BRL","rates":{"USD":0.18,"EUR":0.16}}`)
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = srv.URL
+		d.repo = repo
+		d.cfg.Cotacao.Validade = 0
+	})
+
+	for i := 0; i < 3; i++ {
+		cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC), cotacoes[0].DataHora)
 	}
-	defer func() { services.SecretsFetcher = original }()
 
-	token := services.SecretsFetcher()
-	if token != "" {
-		t.Errorf("Esperava retorno vazio, obteve: %s", token)
+	// Os dois pares têm o mesmo horário sem se sobrescreverem
+	for _, destino := range []string{"USD", "EUR"} {
+		cotacoes, _ := repo.Range(ctx, "BRL", destino, time.Time{}, time.Now())
+		assert.Len(t, cotacoes, 1, destino)
 	}
 }
 
-func TestBuscarAPIKeyDoFixer_JSONInvalidoOthers(t *testing.T) {
-	original := services.SecretsFetcher
-	services.SecretsFetcher = func() string {
-		raw := `{"fixer_api_key":123}` // valor numérico, quebra o json.Unmarshal
-		var parsed map[string]string
-		err := json.Unmarshal([]byte(raw), &parsed)
-		if err != nil {
-			t.Log("Erro ao interpretar JSON simulado")
-			return ""
+func TestAtualizarCotacoes_BoletimAtrasadoNaoBloqueiaOutroPar(t *testing.T) {
+	var consultasUSD atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		horario := "2025-04-16 13:06:00.0"
+		if r.URL.Query().Get("@moeda") == "'USD'" {
+			horario = fmt.Sprintf("2025-04-17 1%d:00:00.0", consultasUSD.Add(1))
 		}
-		return parsed["fixer_api_key"]
+		fmt.Fprintf(w, `{"value":[{"cotacaoCompra":5.0,"cotacaoVenda":5.0,"dataHoraCotacao":%q}]}`, horario)
+	}))
+	defer srv.Close()
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repo
+		d.cfg.Provedores.Ordem = []string{"bcb"}
+		d.cfg.Provedores.BCBURL = srv.URL
+		d.cfg.Cotacao.Validade = 0
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "EUR"})
+		assert.NoError(t, err)
+	}
+
+	// O boletim novo do dólar é gravado mesmo com o do euro parado
+	usd, _ := repo.Range(ctx, "BRL", "USD", time.Time{}, time.Now())
+	eur, _ := repo.Range(ctx, "BRL", "EUR", time.Time{}, time.Now())
+	assert.Len(t, usd, 2)
+	if assert.Len(t, eur, 1) {
+		assert.Equal(t, time.Date(2025, 4, 16, 16, 6, 0, 0, time.UTC), eur[0].DataHora)
 	}
-	defer func() { services.SecretsFetcher = original }()
+}
 
-	token := services.SecretsFetcher()
-	if token != "" {
-		t.Errorf("Esperava falha no unmarshal, obteve: %s", token)
-	}
+func TestDestinosPadrao(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.cfg.Moedas.Permitidas = []string{"BRL", "USD", "EUR"} })
+
+	assert.Equal(t, []string{"USD", "EUR"}, svc.DestinosPadrao("BRL"))
+	assert.Equal(t, []string{"BRL", "EUR"}, svc.DestinosPadrao("USD"))
 }
 
-func TestBuscarAPIKeyDoFixer_ErroAoObterSegredo(t *testing.T) {
-	original := services.GetSecretValueFn
-	services.GetSecretValueFn = func(_ *secretsmanager.Client, _ *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
-		return nil, errors.New("erro simulado")
-	}
-	defer func() { services.GetSecretValueFn = original }()
+func TestAtualizarCotacoes_PropagaErroAoSalvar(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.usarExchangeRateHost(t, `{"base":"BRL","rates":{"USD":0.18}}`)
+		d.repo = repositorioComFalha{}
+	})
+
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
 
-	key := services.BuscarAPIKeyDoFixer()
-	if key != "" {
-		t.Errorf("Esperava string vazia, obteve: %s", key)
+func TestBuscarHistoricoPaginado(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) { d.repo = repo })
+	inicio := time.Date(2025, 4, 20, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.NewFromInt(int64(i)), DataHora: inicio.Add(time.Duration(i) * time.Hour)})
 	}
+
+	pagina, err := svc.BuscarHistoricoPaginado(ctx, "BRL", "USD", inicio, inicio.Add(24*time.Hour), 2, "")
+	assert.NoError(t, err)
+	assert.Len(t, pagina.Items, 2)
+	assert.NotEmpty(t, pagina.NextCursor)
+
+	pagina, err = svc.BuscarHistoricoPaginado(ctx, "BRL", "USD", inicio, inicio.Add(24*time.Hour), 2, pagina.NextCursor)
+	assert.NoError(t, err)
+	assert.Len(t, pagina.Items, 1)
+	assert.Empty(t, pagina.NextCursor)
 }
 
-func TestBuscarAPIKeyDoFixer_ErroJSONUnmarshal(t *testing.T) {
-	original := services.GetSecretValueFn
-	services.GetSecretValueFn = func(_ *secretsmanager.Client, _ *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
-		str := "isso-não-é-json"
-		return &secretsmanager.GetSecretValueOutput{SecretString: &str}, nil
+func TestBuscarHistoricoPaginado_LimiteInvalido(t *testing.T) {
+	_, err := novoService(t, nil).BuscarHistoricoPaginado(ctx, "BRL", "USD", time.Now(), time.Now(), 0, "")
+	assert.Error(t, err)
+}
+
+func TestBuscarHistoricoPaginado_CursorInvalido(t *testing.T) {
+	_, err := novoService(t, nil).BuscarHistoricoPaginado(ctx, "BRL", "USD", time.Now(), time.Now(), 10, "nao-e-cursor")
+	assert.ErrorIs(t, err, services.ErrCursorInvalido)
+	assert.NotErrorIs(t, err, services.ErrArmazenamento)
+}
+
+func TestBuscarHistoricoPaginado_ErroNoArmazenamento(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
+
+	_, err := svc.BuscarHistoricoPaginado(ctx, "BRL", "USD", time.Now(), time.Now(), 10, "")
+	assert.ErrorIs(t, err, services.ErrArmazenamento)
+}
+
+func TestAtualizarCotacoes_ValorDecimalExato(t *testing.T) {
+	repo := repository.NovoMemoryRepository()
+	svc := novoService(t, func(d *dependencias) {
+		d.usarExchangeRateHost(t, `{"base":"BRL","rates":{"USD":0.1,"EUR":0.30000000000000004}}`)
+		d.repo = repo
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD", "EUR"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.1", cotacoes[0].Valor.String())
+	// Arredondado para as 8 casas padrão
+	assert.Equal(t, "0.3", cotacoes[1].Valor.String())
+
+	salva, _ := repo.Latest(ctx, "BRL", "USD")
+	assert.True(t, salva.Valor.Equal(decimal.RequireFromString("0.1")))
+}
+
+func TestAtualizarCotacoes_PrecisaoConfiguravel(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.usarExchangeRateHost(t, `{"base":"BRL","rates":{"USD":0.183456}}`)
+		d.cfg.Cotacao.CasasDecimais = 3
+	})
+
+	cotacoes, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.183", cotacoes[0].Valor.String())
+}
+
+// servidorLento só responde depois de um segundo ou quando a requisição é
+// cancelada.
+func servidorLento(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAtualizarCotacoes_ContextoVencidoInterrompeBusca(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) { d.cfg.Provedores.FixerURL = servidorLento(t).URL })
+	prazo, cancelar := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelar()
+
+	inicio := time.Now()
+	_, err := svc.AtualizarCotacoes(prazo, "BRL", []string{"USD"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(inicio), 500*time.Millisecond)
+}
+
+func TestAtualizarCotacoes_PrazoDaAtualizacao(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.cfg.Provedores.FixerURL = servidorLento(t).URL
+		d.cfg.Prazos.Atualizacao = config.Duracao(20 * time.Millisecond)
+	})
+
+	inicio := time.Now()
+	_, err := svc.AtualizarCotacoes(ctx, "BRL", []string{"USD"})
+
+	assert.ErrorIs(t, err, services.ErrUpstreamIndisponivel)
+	assert.Less(t, time.Since(inicio), 500*time.Millisecond)
+}
+
+// repositorioComPrazo registra o prazo do contexto recebido pelo Save.
+type repositorioComPrazo struct {
+	*repository.MemoryRepository
+	prazo time.Time
+}
+
+func (r *repositorioComPrazo) Save(ctx context.Context, cotacao models.Cotacao) error {
+	r.prazo, _ = ctx.Deadline()
+	return r.MemoryRepository.Save(ctx, cotacao)
+}
+
+func TestSalvarCotacao_PrazoDoArmazenamento(t *testing.T) {
+	repo := &repositorioComPrazo{MemoryRepository: repository.NovoMemoryRepository()}
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repo
+		d.cfg.Prazos.Armazenamento = config.Duracao(2 * time.Second)
+	})
+
+	inicio := time.Now()
+	err := svc.SalvarCotacao(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: inicio})
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, inicio.Add(2*time.Second), repo.prazo, 500*time.Millisecond)
+}
+
+// observacoes conta as observações do histograma com os rótulos dados.
+func observacoes(t *testing.T, h *prometheus.HistogramVec, rotulos ...string) uint64 {
+	var m dto.Metric
+	if err := h.WithLabelValues(rotulos...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
 	}
-	defer func() { services.GetSecretValueFn = original }()
+	return m.GetHistogram().GetSampleCount()
+}
 
-	key := services.BuscarAPIKeyDoFixer()
-	if key != "" {
-		t.Errorf("Esperava string vazia por erro no unmarshal, obteve: %s", key)
+// gravarSpans instala, até o fim do teste, um TracerProvider global que guarda
+// os spans encerrados no gravador retornado.
+func gravarSpans(t *testing.T) *tracetest.SpanRecorder {
+	gravador := tracetest.NewSpanRecorder()
+	anterior := otel.GetTracerProvider()
+	propagador := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(gravador)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(anterior)
+		otel.SetTextMapPropagator(propagador)
+	})
+	return gravador
+}
+
+// spanChamado retorna o span encerrado com o nome dado, falhando o teste se
+// não houver.
+func spanChamado(t *testing.T, gravador *tracetest.SpanRecorder, nome string) sdktrace.ReadOnlySpan {
+	for _, span := range gravador.Ended() {
+		if span.Name() == nome {
+			return span
+		}
 	}
+	t.Fatalf("nenhum span %q", nome)
+	return nil
+}
+
+func TestSalvarCotacao_MedeOArmazenamento(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repositorioComFalha{}
+		d.cfg.Armazenamento.Backend = "memory"
+	})
+	falhas := observacoes(t, metricas.Armazenamento, "memory", "Save", "erro")
+	ausentes := observacoes(t, metricas.Armazenamento, "memory", "Latest", "nao_encontrado")
+
+	svc.SalvarCotacao(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: time.Now()})
+	novoService(t, func(d *dependencias) { d.cfg.Armazenamento.Backend = "memory" }).BuscarUltimaCotacaoSalva(ctx, "BRL", "USD")
+
+	assert.Equal(t, falhas+1, observacoes(t, metricas.Armazenamento, "memory", "Save", "erro"))
+	assert.Equal(t, ausentes+1, observacoes(t, metricas.Armazenamento, "memory", "Latest", "nao_encontrado"))
+}
+
+func TestSalvarCotacao_SpanDoArmazenamento(t *testing.T) {
+	gravador := gravarSpans(t)
+	svc := novoService(t, func(d *dependencias) { d.repo = repositorioComFalha{} })
+
+	svc.SalvarCotacao(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: time.Now()})
+	novoService(t, nil).BuscarUltimaCotacaoSalva(ctx, "BRL", "USD")
+
+	gravacao := spanChamado(t, gravador, "armazenamento Save")
+	assert.Equal(t, codes.Error, gravacao.Status().Code)
+	assert.Contains(t, gravacao.Attributes(), attribute.String("resultado", "erro"))
+
+	leitura := spanChamado(t, gravador, "armazenamento Latest")
+	assert.Equal(t, codes.Unset, leitura.Status().Code, "cotação ausente não é falha do armazenamento")
+	assert.Contains(t, leitura.Attributes(), attribute.String("resultado", "nao_encontrado"))
 }
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"cambio-brl-usd/repository"
+	"errors"
+)
+
+// Erros retornados pelo pacote. As funções envolvem estes valores com o erro
+// original (fmt.Errorf com %w), então use errors.Is para identificá-los.
+var (
+	// ErrUpstreamIndisponivel indica que nenhum provedor de cotações respondeu.
+	ErrUpstreamIndisponivel = errors.New("provedor de cotações indisponível")
+	// ErrCircuitoAberto indica que o provedor não foi chamado porque falhou
+	// seguidamente e está em pausa.
+	ErrCircuitoAberto = errors.New("circuito do provedor aberto")
+	// ErrLimiteRequisicoes indica que o provedor não foi chamado porque a cota
+	// de requisições dele está esgotada.
+	ErrLimiteRequisicoes = errors.New("limite de requisições do provedor esgotado")
+	// ErrNaoEncontrado indica que não há cotação salva para o que foi pedido.
+	ErrNaoEncontrado = repository.ErrNaoEncontrado
+	// ErrCursorInvalido indica um cursor de paginação adulterado ou de outra
+	// consulta.
+	ErrCursorInvalido = repository.ErrCursorInvalido
+	// ErrConfiguracao indica configuração ausente ou inválida (AWS, segredos,
+	// provedores).
+	ErrConfiguracao = errors.New("configuração inválida")
+	// ErrArmazenamento indica falha ao ler ou gravar cotações no banco.
+	ErrArmazenamento = errors.New("falha no armazenamento")
+)
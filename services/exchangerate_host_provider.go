@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+type exchangeRateHostResponse struct {
+	Success   *bool                      `json:"success"`
+	Base      string                     `json:"base"`
+	Timestamp int64                      `json:"timestamp"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+	Error     *struct {
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// ExchangeRateHostProvider busca taxas em APIs no formato do exchangerate.host
+// (GET /latest?base=...&symbols=... respondendo {"base", "rates"}). A chave de
+// acesso é opcional, já que vários espelhos compatíveis não a exigem.
+type ExchangeRateHostProvider struct {
+	URL       string
+	AccessKey string
+	Client    HTTPClient
+}
+
+// NovoExchangeRateHostProvider cria o provedor para a API em url, com a chave
+// de acesso opcional accessKey.
+func NovoExchangeRateHostProvider(url, accessKey string, client HTTPClient) *ExchangeRateHostProvider {
+	return &ExchangeRateHostProvider{URL: url, AccessKey: accessKey, Client: client}
+}
+
+func (p *ExchangeRateHostProvider) Nome() string { return "exchangeratehost" }
+
+func (p *ExchangeRateHostProvider) BuscarTaxas(ctx context.Context, base string, simbolos []string) (Taxas, error) {
+	params := url.Values{}
+	params.Set("base", base)
+	params.Set("symbols", strings.Join(simbolos, ","))
+	if p.AccessKey != "" {
+		params.Set("access_key", p.AccessKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.URL+"/latest?"+params.Encode(), nil)
+	if err != nil {
+		return Taxas{}, fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+
+	var resp exchangeRateHostResponse
+	if err := buscarJSON(p.Client, req, &resp); err != nil {
+		return Taxas{}, err
+	}
+
+	if resp.Success != nil && !*resp.Success {
+		if resp.Error != nil && resp.Error.Info != "" {
+			return Taxas{}, fmt.Errorf("API retornou sucesso=false: %s", resp.Error.Info)
+		}
+		return Taxas{}, fmt.Errorf("API retornou sucesso=false")
+	}
+
+	if len(resp.Rates) == 0 {
+		return Taxas{}, fmt.Errorf("API não retornou taxas")
+	}
+
+	if resp.Base == "" {
+		resp.Base = base
+	}
+	return Taxas{Base: resp.Base, Rates: resp.Rates, DataHora: deUnix(resp.Timestamp)}, nil
+}
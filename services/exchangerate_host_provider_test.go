@@ -0,0 +1,49 @@
+package services_test
+
+import (
+	"cambio-brl-usd/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRateHostProvider_BuscarTaxas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/latest", r.URL.Path)
+		assert.Equal(t, "BRL", r.URL.Query().Get("base"))
+		assert.Equal(t, "USD,GBP", r.URL.Query().Get("symbols"))
+		assert.Equal(t, "chave", r.URL.Query().Get("access_key"))
+		w.Write([]byte(`{"base":"BRL","date":"2025-04-18","rates":{"USD":0.17,"GBP":0.13}}`))
+	}))
+	defer srv.Close()
+
+	provider := services.NovoExchangeRateHostProvider(srv.URL, "chave", srv.Client())
+	taxas, err := provider.BuscarTaxas(ctx, "BRL", []string{"USD", "GBP"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "BRL", taxas.Base)
+	assert.Equal(t, "0.13", taxas.Rates["GBP"].String())
+	assert.Equal(t, "exchangeratehost", provider.Nome())
+}
+
+func TestExchangeRateHostProvider_SucessoFalse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"success":false,"error":{"info":"invalid access key"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := services.NovoExchangeRateHostProvider(srv.URL, "", srv.Client()).BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.ErrorContains(t, err, "invalid access key")
+}
+
+func TestExchangeRateHostProvider_SemTaxas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"base":"BRL","rates":{}}`))
+	}))
+	defer srv.Close()
+
+	_, err := services.NovoExchangeRateHostProvider(srv.URL, "", srv.Client()).BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// FailoverProvider consulta uma lista ordenada de provedores e devolve a
+// resposta do primeiro que atender a todos os símbolos pedidos.
+type FailoverProvider struct {
+	Providers []RateProvider
+}
+
+// NovoFailoverProvider monta a cadeia com os provedores de nomes, na ordem de
+// preferência (ex.: fixer, bcb, exchangeratehost). Os demais argumentos são
+// repassados a NovoRateProvider; cada provedor faz as requisições por client
+// através do próprio ClienteResiliente, para que a falha de um não abra o
+// circuito dos outros. A pausa do circuito é contada pelo relógio agora.
+func NovoFailoverProvider(nomes []string, cfg config.Provedores, client HTTPClient, segredos SecretSource, agora func() time.Time) (*FailoverProvider, error) {
+	cadeia := &FailoverProvider{}
+	for _, nome := range nomes {
+		nome = strings.ToLower(strings.TrimSpace(nome))
+		if nome == "" {
+			continue
+		}
+		resiliente := NovoClienteResiliente(nome, client, cfg, agora)
+		provider, err := NovoRateProvider(nome, cfg, resiliente, segredos)
+		if err != nil {
+			return nil, err
+		}
+		cadeia.Providers = append(cadeia.Providers, provider)
+	}
+
+	if len(cadeia.Providers) == 0 {
+		return nil, fmt.Errorf("%w: nenhum provedor de cotações configurado", ErrConfiguracao)
+	}
+	return cadeia, nil
+}
+
+func (f *FailoverProvider) Nome() string {
+	nomes := make([]string, len(f.Providers))
+	for i, p := range f.Providers {
+		nomes[i] = p.Nome()
+	}
+	return strings.Join(nomes, ",")
+}
+
+// BuscarTaxas tenta cada provedor em ordem. Uma resposta sem algum dos
+// símbolos pedidos conta como falha e passa para o próximo provedor. Se todos
+// falharem, o erro retornado agrega o motivo de cada um. Com ctx cancelado ou
+// vencido, os provedores seguintes não são consultados.
+func (f *FailoverProvider) BuscarTaxas(ctx context.Context, base string, simbolos []string) (Taxas, error) {
+	var erros []error
+	for i, provider := range f.Providers {
+		taxas, err := provider.BuscarTaxas(ctx, base, simbolos)
+		if err == nil {
+			err = verificarSimbolos(taxas, simbolos)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "provedor falhou", "provedor", provider.Nome(), "erro", err)
+			erros = append(erros, fmt.Errorf("%s: %w", provider.Nome(), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		taxas.Provedor = provider.Nome()
+		taxas.Contingencia = i > 0
+		if taxas.Contingencia {
+			metricas.Contingencia.WithLabelValues(taxas.Provedor).Inc()
+		}
+		return taxas, nil
+	}
+	metricas.Contingencia.WithLabelValues("nenhum").Inc()
+	return Taxas{}, errors.Join(erros...)
+}
+
+func verificarSimbolos(taxas Taxas, simbolos []string) error {
+	for _, simbolo := range simbolos {
+		if _, ok := taxas.Rates[simbolo]; !ok {
+			return fmt.Errorf("API não retornou a moeda: %s", simbolo)
+		}
+	}
+	return nil
+}
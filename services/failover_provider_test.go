@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/metricas"
+	"cambio-brl-usd/services"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type providerFake struct {
+	nome  string
+	taxas services.Taxas
+	err   error
+}
+
+func (p providerFake) Nome() string { return p.nome }
+
+func (p providerFake) BuscarTaxas(context.Context, string, []string) (services.Taxas, error) {
+	return p.taxas, p.err
+}
+
+func TestFailoverProvider_UsaPrimeiroQueResponde(t *testing.T) {
+	cadeia := &services.FailoverProvider{Providers: []services.RateProvider{
+		providerFake{nome: "a", err: errors.New("fora do ar")},
+		providerFake{nome: "b", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.2")}}},
+		providerFake{nome: "c", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.3")}}},
+	}}
+	contingencias := testutil.ToFloat64(metricas.Contingencia.WithLabelValues("b"))
+
+	taxas, err := cadeia.BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, contingencias+1, testutil.ToFloat64(metricas.Contingencia.WithLabelValues("b")))
+	assert.Equal(t, "0.2", taxas.Rates["USD"].String())
+	assert.Equal(t, "b", taxas.Provedor)
+	assert.True(t, taxas.Contingencia)
+	assert.Equal(t, "a,b,c", cadeia.Nome())
+}
+
+func TestFailoverProvider_PrimeiroProvedorNaoEContingencia(t *testing.T) {
+	cadeia := &services.FailoverProvider{Providers: []services.RateProvider{
+		providerFake{nome: "a", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.2")}}},
+		providerFake{nome: "b", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.3")}}},
+	}}
+
+	taxas, err := cadeia.BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a", taxas.Provedor)
+	assert.False(t, taxas.Contingencia)
+}
+
+func TestFailoverProvider_RespostaIncompletaContaComoFalha(t *testing.T) {
+	cadeia := &services.FailoverProvider{Providers: []services.RateProvider{
+		providerFake{nome: "a", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.2")}}},
+		providerFake{nome: "b", taxas: services.Taxas{Base: "BRL", Rates: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.2"), "ARS": decimal.RequireFromString("190")}}},
+	}}
+
+	taxas, err := cadeia.BuscarTaxas(ctx, "BRL", []string{"USD", "ARS"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", taxas.Provedor)
+}
+
+func TestFailoverProvider_TodosFalham(t *testing.T) {
+	cadeia := &services.FailoverProvider{Providers: []services.RateProvider{
+		providerFake{nome: "a", err: errors.New("erro a")},
+		providerFake{nome: "b", err: errors.New("erro b")},
+	}}
+	semProvedor := testutil.ToFloat64(metricas.Contingencia.WithLabelValues("nenhum"))
+
+	_, err := cadeia.BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.Equal(t, semProvedor+1, testutil.ToFloat64(metricas.Contingencia.WithLabelValues("nenhum")))
+
+	assert.ErrorContains(t, err, "a: erro a")
+	assert.ErrorContains(t, err, "b: erro b")
+}
+
+func TestNovoFailoverProvider(t *testing.T) {
+	cfg := config.Padrao().Provedores
+
+	cadeia, err := services.NovoFailoverProvider([]string{"fixer", " bcb", ""}, cfg, http.DefaultClient, nil, time.Now)
+	assert.NoError(t, err)
+	assert.Equal(t, "fixer,bcb", cadeia.Nome())
+
+	_, err = services.NovoFailoverProvider([]string{"fixer", "inexistente"}, cfg, http.DefaultClient, nil, time.Now)
+	assert.Error(t, err)
+
+	_, err = services.NovoFailoverProvider([]string{" ", ""}, cfg, http.DefaultClient, nil, time.Now)
+	assert.Error(t, err)
+}
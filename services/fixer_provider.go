@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type apiResponse struct {
+	Base      string                     `json:"base"`
+	Success   bool                       `json:"success"`
+	Timestamp int64                      `json:"timestamp"`
+	Date      string                     `json:"date"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+	Error     struct {
+		Code int    `json:"code"`
+		Type string `json:"type"`
+	} `json:"error"`
+}
+
+// errChaveRecusada indica que o Fixer não aceitou a chave de API enviada.
+var errChaveRecusada = errors.New("chave de API recusada pelo Fixer")
+
+// FixerProvider busca taxas na API do Fixer (apilayer), autenticando com a
+// chave obtida de Segredos.
+type FixerProvider struct {
+	URL      string
+	Client   HTTPClient
+	Segredos SecretSource
+}
+
+// NovoFixerProvider cria o provedor para a API em url.
+func NovoFixerProvider(url string, client HTTPClient, segredos SecretSource) *FixerProvider {
+	return &FixerProvider{URL: url, Client: client, Segredos: segredos}
+}
+
+func (p *FixerProvider) Nome() string { return "fixer" }
+
+// BuscarTaxas consulta o Fixer. Se a chave for recusada e Segredos a guardar
+// em cache (ChaveRejeitada), a chave é descartada e a consulta é repetida uma
+// vez com a versão atual do segredo, o que cobre rotações da chave.
+func (p *FixerProvider) BuscarTaxas(ctx context.Context, base string, simbolos []string) (Taxas, error) {
+	token, err := p.Segredos.APIKey(ctx)
+	if err != nil {
+		return Taxas{}, err
+	}
+
+	taxas, err := p.buscar(ctx, token, base, simbolos)
+	if cache, ok := p.Segredos.(ChaveRejeitada); ok && errors.Is(err, errChaveRecusada) {
+		slog.WarnContext(ctx, "chave recusada pelo Fixer, relendo o segredo")
+		cache.ChaveRejeitada(token)
+
+		novo, errSegredo := p.Segredos.APIKey(ctx)
+		if errSegredo != nil {
+			return Taxas{}, errSegredo
+		}
+		if novo != token {
+			return p.buscar(ctx, novo, base, simbolos)
+		}
+	}
+	return taxas, err
+}
+
+func (p *FixerProvider) buscar(ctx context.Context, token, base string, simbolos []string) (Taxas, error) {
+	url := fmt.Sprintf("%s/latest?base=%s&symbols=%s", p.URL, base, strings.Join(simbolos, ","))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return Taxas{}, fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+
+	req.Header.Add("apikey", token)
+
+	var apiResp apiResponse
+	if err := buscarJSON(p.Client, req, &apiResp); err != nil {
+		var status *erroStatus
+		if errors.As(err, &status) && status.Status == http.StatusUnauthorized {
+			return Taxas{}, fmt.Errorf("%w: %w", errChaveRecusada, err)
+		}
+		return Taxas{}, err
+	}
+
+	if !apiResp.Success {
+		// A API antiga do Fixer responde 200 com o erro 101 para chaves inválidas
+		if apiResp.Error.Code == 101 || strings.HasSuffix(apiResp.Error.Type, "_access_key") {
+			return Taxas{}, fmt.Errorf("%w: %s", errChaveRecusada, apiResp.Error.Type)
+		}
+		return Taxas{}, fmt.Errorf("API retornou sucesso=false")
+	}
+
+	return Taxas{Base: apiResp.Base, Rates: apiResp.Rates, DataHora: apiResp.dataHora()}, nil
+}
+
+// dataHora é o horário das taxas: o timestamp ou, se ele faltar, o início do
+// dia em date (UTC). Sem nenhum dos dois, é zero.
+func (r apiResponse) dataHora() time.Time {
+	if r.Timestamp > 0 {
+		return deUnix(r.Timestamp)
+	}
+	dia, err := time.Parse(time.DateOnly, r.Date)
+	if err != nil {
+		return time.Time{}
+	}
+	return dia
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/services"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// comToken é uma fonte que sempre devolve token.
+func comToken(token string) services.SecretSource {
+	return services.SecretSourceFunc(func(context.Context) (string, error) { return token, nil })
+}
+
+func TestFixerProvider_BuscarTaxas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/latest", r.URL.Path)
+		assert.Equal(t, "BRL", r.URL.Query().Get("base"))
+		assert.Equal(t, "USD,EUR", r.URL.Query().Get("symbols"))
+		assert.Equal(t, "token", r.Header.Get("apikey"))
+		w.Write([]byte(`{"success":true,"timestamp":1745236800,"base":"BRL","rates":{"USD":0.17,"EUR":0.16}}`))
+	}))
+	defer srv.Close()
+
+	provider := services.NovoFixerProvider(srv.URL, srv.Client(), comToken("token"))
+	taxas, err := provider.BuscarTaxas(ctx, "BRL", []string{"USD", "EUR"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "BRL", taxas.Base)
+	assert.Equal(t, time.Date(2025, 4, 21, 12, 0, 0, 0, time.UTC), taxas.DataHora)
+	assert.Equal(t, "0.17", taxas.Rates["USD"].String())
+	assert.Equal(t, "0.16", taxas.Rates["EUR"].String())
+	assert.Equal(t, "fixer", provider.Nome())
+}
+
+func TestFixerProvider_SemToken(t *testing.T) {
+	segredos := services.SecretSourceFunc(func(context.Context) (string, error) { return "", services.ErrConfiguracao })
+
+	_, err := services.NovoFixerProvider("http://nao-usado", http.DefaultClient, segredos).BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.Error(t, err)
+}
+
+func TestFixerProvider_StatusDeErro(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := services.NovoFixerProvider(srv.URL, srv.Client(), comToken("token")).BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.ErrorContains(t, err, "429")
+}
+
+func TestFixerProvider_ChaveRotacionada(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("apikey") != "v2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"success":true,"base":"BRL","rates":{"USD":0.17}}`))
+	}))
+	defer srv.Close()
+
+	fonte := &segredoRotativo{chave: "v1"}
+	r := &relogio{agora: time.Now()}
+	cache := services.NovoCacheSegredo(fonte, time.Hour, r.Now)
+	provider := services.NovoFixerProvider(srv.URL, srv.Client(), cache)
+
+	_, err := provider.BuscarTaxas(ctx, "BRL", []string{"USD"})
+	assert.ErrorContains(t, err, "401")
+
+	fonte.rotacionar("v2", nil)
+	r.agora = r.agora.Add(services.EsperaSegredoPadrao)
+	taxas, err := provider.BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.17", taxas.Rates["USD"].String())
+	assert.Equal(t, 3, fonte.contagem())
+}
+
+func TestFixerProvider_ChaveInvalidaNaAPIAntiga(t *testing.T) {
+	chaves := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chaves = append(chaves, r.Header.Get("apikey"))
+		w.Write([]byte(`{"success":false,"error":{"code":101,"type":"invalid_access_key"}}`))
+	}))
+	defer srv.Close()
+
+	fonte := &segredoRotativo{chave: "v1"}
+	provider := services.NovoFixerProvider(srv.URL, srv.Client(), services.NovoCacheSegredo(fonte, time.Hour, time.Now))
+
+	_, err := provider.BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.ErrorContains(t, err, "invalid_access_key")
+	// Segredo relido, mas sem rotação: não repete a consulta com a mesma chave
+	assert.Equal(t, []string{"v1"}, chaves)
+	assert.Equal(t, 2, fonte.contagem())
+}
+
+func TestNovoRateProvider(t *testing.T) {
+	for nome, esperado := range map[string]string{
+		"":                 "fixer",
+		"FIXER":            "fixer",
+		"bcb":              "bcb",
+		"ptax":             "bcb",
+		"exchangeratehost": "exchangeratehost",
+	} {
+		provider, err := services.NovoRateProvider(nome, config.Padrao().Provedores, http.DefaultClient, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, esperado, provider.Nome())
+	}
+
+	_, err := services.NovoRateProvider("desconhecido", config.Padrao().Provedores, http.DefaultClient, nil)
+	assert.Error(t, err)
+}
+
+func TestNovoRateProvider_UsaURLConfigurada(t *testing.T) {
+	cfg := config.Padrao().Provedores
+	cfg.BCBURL = "http://bcb.local"
+
+	provider, err := services.NovoRateProvider("bcb", cfg, http.DefaultClient, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://bcb.local", provider.(*services.BCBProvider).URL)
+}
+
+func TestFixerProvider_DataSemTimestamp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"success":true,"date":"2025-04-21","base":"BRL","rates":{"USD":0.17}}`))
+	}))
+	defer srv.Close()
+
+	taxas, err := services.NovoFixerProvider(srv.URL, srv.Client(), comToken("token")).BuscarTaxas(ctx, "BRL", []string{"USD"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC), taxas.DataHora)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Nomes das dependências verificadas por Prontidao.
+const (
+	DependenciaArmazenamento = "armazenamento"
+	DependenciaSegredo       = "segredo"
+	DependenciaIngestao      = "ingestao"
+)
+
+// verificacao checa uma dependência, podendo completar dep com detalhes.
+type verificacao func(ctx context.Context, dep *models.Dependencia) error
+
+// Prontidao verifica, em paralelo, as dependências de que a API precisa para
+// atender: o banco de cotações, a chave do Fixer (só se ele estiver em
+// Config.Provedores.Ordem) e a ingestão, que falha quando nenhuma atualização
+// obtém cotações da moeda pivô há mais de Config.Prontidao.IdadeMaximaIngestao.
+// Cada verificação tem o prazo do armazenamento ou do segredo.
+func (s *CotacaoService) Prontidao(ctx context.Context) models.Prontidao {
+	verificacoes := map[string]verificacao{
+		DependenciaArmazenamento: s.verificarArmazenamento,
+		DependenciaIngestao:      s.verificarIngestao,
+	}
+	if slices.Contains(s.cfg.Provedores.Ordem, "fixer") {
+		verificacoes[DependenciaSegredo] = s.verificarSegredo
+	}
+
+	prontidao := models.Prontidao{Status: models.StatusOK, Dependencias: map[string]models.Dependencia{}}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for nome, verificar := range verificacoes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inicio := time.Now()
+			dep := models.Dependencia{Status: models.StatusOK}
+			err := verificar(ctx, &dep)
+			dep.DuracaoMs = time.Since(inicio).Milliseconds()
+			if err != nil {
+				dep.Status = models.StatusFalha
+				dep.Erro = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			prontidao.Dependencias[nome] = dep
+			if err != nil {
+				prontidao.Status = models.StatusFalha
+			}
+		}()
+	}
+	wg.Wait()
+	return prontidao
+}
+
+// verificarArmazenamento pede ao repositório que confira o banco. Repositórios
+// sem banco externo (memória) estão sempre disponíveis.
+func (s *CotacaoService) verificarArmazenamento(ctx context.Context, _ *models.Dependencia) error {
+	verificador, ok := s.repo.(repository.Verificador)
+	if !ok {
+		return nil
+	}
+
+	ctx, concluir := s.operacao(ctx, "Verificar")
+	err := verificador.Verificar(ctx)
+	concluir(err)
+	return err
+}
+
+// verificarSegredo confere que a chave do Fixer pode ser obtida. Com
+// CacheSegredo, uma chave ainda em cache basta.
+func (s *CotacaoService) verificarSegredo(ctx context.Context, _ *models.Dependencia) error {
+	ctx, cancelar := comPrazo(ctx, s.cfg.Prazos.Segredo)
+	defer cancelar()
+
+	_, err := s.segredos.APIKey(ctx)
+	return err
+}
+
+// verificarIngestao procura, entre as últimas cotações gravadas da moeda pivô
+// para as demais moedas permitidas, que é o que a Lambda agendada grava, a
+// obtida mais recentemente. Conta o horário da obtenção, e não o das taxas,
+// que não muda quando o provedor não publica nada novo.
+func (s *CotacaoService) verificarIngestao(ctx context.Context, dep *models.Dependencia) error {
+	pivo := s.cfg.Moedas.Pivo
+
+	var ultima time.Time
+	for _, destino := range s.DestinosPadrao(pivo) {
+		cotacao, err := s.BuscarUltimaCotacaoSalva(ctx, pivo, destino)
+		if errors.Is(err, ErrNaoEncontrado) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if em := obtidaEm(cotacao); em.After(ultima) {
+			ultima = em
+		}
+	}
+	if ultima.IsZero() {
+		return fmt.Errorf("nenhuma cotação de %s gravada", pivo)
+	}
+
+	idade := s.agora().Sub(ultima)
+	dep.UltimaIngestao = &ultima
+	dep.Idade = idade.Truncate(time.Second).String()
+	if maxima := time.Duration(s.cfg.Prontidao.IdadeMaximaIngestao); maxima > 0 && idade > maxima {
+		return fmt.Errorf("nenhuma cotação de %s obtida há %s, mais que %s", pivo, dep.Idade, maxima)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"cambio-brl-usd/models"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// ingestoes retorna um armazenamento com cotações do pivô BRL obtidas nos
+// horários dados, com taxas de uma hora antes.
+func ingestoes(obtidas map[string]time.Time) *repository.MemoryRepository {
+	repo := repository.NovoMemoryRepository()
+	for destino, obtidaEm := range obtidas {
+		repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: destino, Valor: decimal.RequireFromString("0.18"),
+			DataHora: obtidaEm.Add(-time.Hour), ObtidaEm: &obtidaEm})
+	}
+	return repo
+}
+
+func TestProntidao_TudoDisponivel(t *testing.T) {
+	agora := time.Date(2025, 4, 21, 13, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = ingestoes(map[string]time.Time{
+			"USD": agora.Add(-40 * time.Minute),
+			"EUR": agora.Add(-10 * time.Minute),
+		})
+		d.agora = func() time.Time { return agora }
+	})
+
+	prontidao := svc.Prontidao(ctx)
+
+	assert.Equal(t, models.StatusOK, prontidao.Status)
+	assert.Len(t, prontidao.Dependencias, 3)
+	for nome, dep := range prontidao.Dependencias {
+		assert.Equal(t, models.StatusOK, dep.Status, nome)
+		assert.Empty(t, dep.Erro, nome)
+	}
+	ingestao := prontidao.Dependencias[services.DependenciaIngestao]
+	assert.Equal(t, "10m0s", ingestao.Idade, "vale a cotação obtida mais recentemente")
+	assert.Equal(t, agora.Add(-10*time.Minute), *ingestao.UltimaIngestao)
+}
+
+func TestProntidao_TaxasAntigasObtidasAgora(t *testing.T) {
+	// No fim de semana o provedor devolve as taxas de sexta-feira, que a
+	// atualização grava de novo só com o horário da obtenção
+	agora := time.Date(2025, 4, 20, 8, 5, 0, 0, time.UTC)
+	sexta := time.Date(2025, 4, 18, 20, 0, 0, 0, time.UTC)
+	obtidaEm := agora.Add(-5 * time.Minute)
+	repo := repository.NovoMemoryRepository()
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: sexta})
+	repo.Save(ctx, models.Cotacao{MoedaOrigem: "BRL", MoedaDestino: "USD", Valor: decimal.RequireFromString("0.18"), DataHora: sexta, ObtidaEm: &obtidaEm})
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repo
+		d.agora = func() time.Time { return agora }
+	})
+
+	prontidao := svc.Prontidao(ctx)
+
+	assert.Equal(t, models.StatusOK, prontidao.Status)
+	assert.Equal(t, "5m0s", prontidao.Dependencias[services.DependenciaIngestao].Idade)
+}
+
+func TestProntidao_IngestaoAtrasada(t *testing.T) {
+	agora := time.Date(2025, 4, 21, 13, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = ingestoes(map[string]time.Time{"USD": agora.Add(-14 * time.Hour)})
+		d.agora = func() time.Time { return agora }
+	})
+
+	prontidao := svc.Prontidao(ctx)
+
+	assert.Equal(t, models.StatusFalha, prontidao.Status)
+	assert.Equal(t, models.StatusOK, prontidao.Dependencias[services.DependenciaArmazenamento].Status)
+	ingestao := prontidao.Dependencias[services.DependenciaIngestao]
+	assert.Equal(t, models.StatusFalha, ingestao.Status)
+	assert.Equal(t, "nenhuma cotação de BRL obtida há 14h0m0s, mais que 13h0m0s", ingestao.Erro)
+	assert.Equal(t, "14h0m0s", ingestao.Idade)
+}
+
+func TestProntidao_IdadeMaximaZeroNuncaAtrasa(t *testing.T) {
+	agora := time.Date(2025, 4, 21, 13, 0, 0, 0, time.UTC)
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = ingestoes(map[string]time.Time{"USD": agora.Add(-72 * time.Hour)})
+		d.agora = func() time.Time { return agora }
+		d.cfg.Prontidao.IdadeMaximaIngestao = 0
+	})
+
+	assert.Equal(t, models.StatusOK, svc.Prontidao(ctx).Status)
+}
+
+func TestProntidao_SemCotacoesGravadas(t *testing.T) {
+	prontidao := novoService(t, nil).Prontidao(ctx)
+
+	assert.Equal(t, models.StatusFalha, prontidao.Status)
+	assert.Equal(t, "nenhuma cotação de BRL gravada", prontidao.Dependencias[services.DependenciaIngestao].Erro)
+	assert.Nil(t, prontidao.Dependencias[services.DependenciaIngestao].UltimaIngestao)
+}
+
+func TestProntidao_DependenciasIndisponiveis(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.repo = repositorioComFalha{}
+		d.segredos = segredoAusente
+	})
+
+	prontidao := svc.Prontidao(ctx)
+
+	assert.Equal(t, models.StatusFalha, prontidao.Status)
+	assert.Equal(t, "erro simulado", prontidao.Dependencias[services.DependenciaArmazenamento].Erro)
+	assert.Equal(t, "segredo ausente", prontidao.Dependencias[services.DependenciaSegredo].Erro)
+	assert.Contains(t, prontidao.Dependencias[services.DependenciaIngestao].Erro, "erro simulado")
+}
+
+func TestProntidao_SemFixerNaoVerificaOSegredo(t *testing.T) {
+	svc := novoService(t, func(d *dependencias) {
+		d.segredos = segredoAusente
+		d.cfg.Provedores.Ordem = []string{"bcb"}
+	})
+
+	prontidao := svc.Prontidao(ctx)
+
+	assert.NotContains(t, prontidao.Dependencias, services.DependenciaSegredo)
+	assert.Contains(t, prontidao.Dependencias, services.DependenciaArmazenamento)
+}
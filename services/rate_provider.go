@@ -0,0 +1,101 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Taxas é o resultado de uma consulta a um RateProvider: quanto vale uma
+// unidade da moeda Base em cada uma das moedas de Rates. DataHora é o horário
+// das taxas informado pelo provedor (zero se ele não informar); provedores
+// cujas taxas têm horários diferentes por moeda os informam em DatasHoras
+// (veja DataHoraDe). Provedor e Contingencia são preenchidos pelo
+// FailoverProvider com o nome de quem atendeu a consulta e se ele não era o
+// primeiro da ordem.
+type Taxas struct {
+	Base         string
+	Rates        map[string]decimal.Decimal
+	DataHora     time.Time
+	DatasHoras   map[string]time.Time
+	Provedor     string
+	Contingencia bool
+}
+
+// DataHoraDe retorna o horário da taxa de simbolo: o de DatasHoras, se
+// houver, ou DataHora.
+func (t Taxas) DataHoraDe(simbolo string) time.Time {
+	if dataHora, ok := t.DatasHoras[simbolo]; ok {
+		return dataHora
+	}
+	return t.DataHora
+}
+
+// deUnix converte o timestamp em segundos das APIs para time.Time; zero
+// continua zero.
+func deUnix(timestamp int64) time.Time {
+	if timestamp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0).UTC()
+}
+
+// RateProvider é uma fonte externa de taxas de câmbio.
+type RateProvider interface {
+	// Nome identifica o provedor nos logs e na configuração.
+	Nome() string
+	// BuscarTaxas retorna a taxa de base para cada moeda em simbolos. As
+	// requisições ao provedor são canceladas junto com ctx.
+	BuscarTaxas(ctx context.Context, base string, simbolos []string) (Taxas, error)
+}
+
+// NovoRateProvider cria o provedor identificado por nome ("fixer", "bcb" ou
+// "exchangeratehost"), com o endereço de cfg, fazendo as requisições por
+// client. Nome vazio seleciona o Fixer, que obtém sua chave de segredos.
+func NovoRateProvider(nome string, cfg config.Provedores, client HTTPClient, segredos SecretSource) (RateProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(nome)) {
+	case "", "fixer":
+		return NovoFixerProvider(cfg.FixerURL, client, segredos), nil
+	case "bcb", "ptax":
+		return NovoBCBProvider(cfg.BCBURL, client), nil
+	case "exchangeratehost":
+		return NovoExchangeRateHostProvider(cfg.ExchangeRateURL, cfg.ExchangeRateAccessKey, client), nil
+	default:
+		return nil, fmt.Errorf("%w: provedor de cotações desconhecido: %q", ErrConfiguracao, nome)
+	}
+}
+
+// erroStatus é devolvido por buscarJSON quando o provedor responde com um
+// status diferente de 200.
+type erroStatus struct {
+	Status int
+}
+
+func (e *erroStatus) Error() string {
+	return fmt.Sprintf("provedor respondeu com status %d", e.Status)
+}
+
+// buscarJSON executa a requisição por client e decodifica o corpo da resposta
+// em destino.
+func buscarJSON(client HTTPClient, req *http.Request, destino any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao buscar cotação: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &erroStatus{Status: resp.StatusCode}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(destino); err != nil {
+		return fmt.Errorf("erro ao decodificar JSON: %w", err)
+	}
+	return nil
+}
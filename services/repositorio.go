@@ -0,0 +1,40 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/repository"
+	"errors"
+	"fmt"
+)
+
+// NovoRepositorio cria o armazenamento descrito por cfg.Backend ("dynamodb",
+// "memory" ou "sqlite"). O backend dynamodb usa o cliente dynamo, criado uma
+// vez e compartilhado (veja ClientesAWS).
+func NovoRepositorio(cfg config.Armazenamento, dynamo repository.DynamoAPI) (repository.CotacaoRepository, error) {
+	switch cfg.Backend {
+	case "dynamodb":
+		if dynamo == nil {
+			return nil, fmt.Errorf("%w: cliente do DynamoDB não informado", ErrConfiguracao)
+		}
+		return repository.NovoDynamoRepository(dynamo, cfg.TabelaDynamo), nil
+	case "memory":
+		return repository.NovoMemoryRepository(), nil
+	case "sqlite":
+		repo, err := repository.NovoSQLiteRepository(cfg.CaminhoSQLite)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrConfiguracao, err)
+		}
+		return repo, nil
+	default:
+		return nil, fmt.Errorf("%w: armazenamento desconhecido: %q", ErrConfiguracao, cfg.Backend)
+	}
+}
+
+// erroArmazenamento marca err como ErrArmazenamento, exceto quando é apenas a
+// ausência da cotação procurada ou um cursor inválido enviado pelo cliente.
+func erroArmazenamento(err error) error {
+	if errors.Is(err, ErrNaoEncontrado) || errors.Is(err, ErrCursorInvalido) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrArmazenamento, err)
+}
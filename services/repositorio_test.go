@@ -0,0 +1,50 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/repository"
+	"cambio-brl-usd/services"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNovoRepositorio(t *testing.T) {
+	cfg := config.Padrao().Armazenamento
+
+	cfg.Backend = "memory"
+	repo, err := services.NovoRepositorio(cfg, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &repository.MemoryRepository{}, repo)
+
+	cfg.Backend = "sqlite"
+	cfg.CaminhoSQLite = filepath.Join(t.TempDir(), "cotacoes.db")
+	repo, err = services.NovoRepositorio(cfg, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &repository.SQLiteRepository{}, repo)
+	repo.(*repository.SQLiteRepository).Close()
+
+	cfg.Backend = "dynamodb"
+	repo, err = services.NovoRepositorio(cfg, dynamodb.NewFromConfig(aws.Config{Region: "us-east-1"}))
+	assert.NoError(t, err)
+	assert.IsType(t, &repository.DynamoRepository{}, repo)
+
+	cfg.Backend = "postgres"
+	_, err = services.NovoRepositorio(cfg, nil)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestNovoRepositorio_DynamoSemCliente(t *testing.T) {
+	_, err := services.NovoRepositorio(config.Padrao().Armazenamento, nil)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestNovoRepositorio_SQLiteInvalido(t *testing.T) {
+	cfg := config.Armazenamento{Backend: "sqlite", CaminhoSQLite: filepath.Join(t.TempDir(), "nao-existe", "cotacoes.db")}
+
+	_, err := services.NovoRepositorio(cfg, nil)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
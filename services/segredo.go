@@ -0,0 +1,168 @@
+package services
+
+import (
+	"cambio-brl-usd/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SecretSource fornece a chave de API do Fixer. As fontes que consultam a AWS
+// param quando ctx é cancelado.
+type SecretSource interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// SecretSourceFunc adapta uma função comum a SecretSource.
+type SecretSourceFunc func(ctx context.Context) (string, error)
+
+func (f SecretSourceFunc) APIKey(ctx context.Context) (string, error) { return f(ctx) }
+
+// ChaveJSONPadrao é o campo lido de segredos do Secrets Manager quando nenhum
+// outro é configurado.
+const ChaveJSONPadrao = "fixer_api_key"
+
+// NovoSecretSource cria a fonte escolhida em cfg.Fonte ("secretsmanager",
+// "ssm", "env" ou "file"). As fontes da AWS usam os clientes compartilhados
+// de clientes.
+func NovoSecretSource(cfg config.Segredo, clientes *ClientesAWS) (SecretSource, error) {
+	switch cfg.Fonte {
+	case "", "secretsmanager":
+		source := NovoSecretsManagerSource(clientes.SecretsManager, cfg.Nome)
+		if cfg.ChaveJSON != "" {
+			source.Chave = cfg.ChaveJSON
+		}
+		return source, nil
+	case "ssm":
+		return &SSMSource{Client: clientes.SSM, Nome: cfg.Nome, Chave: cfg.ChaveJSON}, nil
+	case "env":
+		return &EnvSource{Variavel: cfg.Variavel, Chave: cfg.ChaveJSON}, nil
+	case "file":
+		return &ArquivoSource{Caminho: cfg.Arquivo, Chave: cfg.ChaveJSON}, nil
+	default:
+		return nil, fmt.Errorf("%w: fonte de segredo desconhecida: %q", ErrConfiguracao, cfg.Fonte)
+	}
+}
+
+// SecretsManagerAPI é a parte do cliente do Secrets Manager usada por
+// SecretsManagerSource.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SecretsManagerSource lê a chave do Fixer do campo Chave (padrão
+// fixer_api_key) do segredo Nome, guardado como JSON no Secrets Manager.
+type SecretsManagerSource struct {
+	Client SecretsManagerAPI
+	Nome   string
+	Chave  string
+}
+
+// NovoSecretsManagerSource cria a fonte para o segredo nome, consultado pelo
+// cliente compartilhado client (veja ClientesAWS).
+func NovoSecretsManagerSource(client SecretsManagerAPI, nome string) *SecretsManagerSource {
+	return &SecretsManagerSource{Client: client, Nome: nome, Chave: ChaveJSONPadrao}
+}
+
+// APIKey consulta o segredo a cada chamada.
+func (s *SecretsManagerSource) APIKey(ctx context.Context) (string, error) {
+	result, err := s.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.Nome),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: erro ao obter segredo %s: %w", ErrConfiguracao, s.Nome, err)
+	}
+
+	chave := s.Chave
+	if chave == "" {
+		chave = ChaveJSONPadrao
+	}
+	return extrairChave(aws.ToString(result.SecretString), chave, "segredo "+s.Nome)
+}
+
+// SSMAPI é a parte do cliente do SSM usada por SSMSource.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SSMSource lê a chave do parâmetro Nome do SSM Parameter Store,
+// descriptografando parâmetros SecureString. Com Chave preenchida o valor é
+// um JSON e a chave é o campo Chave; vazio, o valor é a própria chave.
+type SSMSource struct {
+	Client SSMAPI
+	Nome   string
+	Chave  string
+}
+
+// APIKey consulta o parâmetro a cada chamada.
+func (s *SSMSource) APIKey(ctx context.Context) (string, error) {
+	result, err := s.Client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.Nome),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: erro ao obter parâmetro %s: %w", ErrConfiguracao, s.Nome, err)
+	}
+	if result.Parameter == nil {
+		return "", fmt.Errorf("%w: parâmetro %s sem valor", ErrConfiguracao, s.Nome)
+	}
+	return extrairChave(aws.ToString(result.Parameter.Value), s.Chave, "parâmetro "+s.Nome)
+}
+
+// EnvSource lê a chave da variável de ambiente Variavel, para execuções
+// locais. Chave funciona como em SSMSource.
+type EnvSource struct {
+	Variavel string
+	Chave    string
+}
+
+func (s *EnvSource) APIKey(context.Context) (string, error) {
+	return extrairChave(os.Getenv(s.Variavel), s.Chave, "variável "+s.Variavel)
+}
+
+// ArquivoSource lê a chave do arquivo Caminho, como os segredos montados pelo
+// Kubernetes ou pelo Docker. O arquivo é relido a cada chamada, então uma
+// rotação vale assim que o arquivo é atualizado. Chave funciona como em
+// SSMSource.
+type ArquivoSource struct {
+	Caminho string
+	Chave   string
+}
+
+func (s *ArquivoSource) APIKey(context.Context) (string, error) {
+	dados, err := os.ReadFile(s.Caminho)
+	if err != nil {
+		return "", fmt.Errorf("%w: erro ao ler arquivo de segredo: %w", ErrConfiguracao, err)
+	}
+	return extrairChave(string(dados), s.Chave, "arquivo "+s.Caminho)
+}
+
+// extrairChave devolve o campo chave do JSON em valor ou, com chave vazia, o
+// próprio valor sem espaços. origem identifica o segredo nas mensagens de
+// erro.
+func extrairChave(valor, chave, origem string) (string, error) {
+	if chave == "" {
+		token := strings.TrimSpace(valor)
+		if token == "" {
+			return "", fmt.Errorf("%w: %s vazio", ErrConfiguracao, origem)
+		}
+		return token, nil
+	}
+
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(valor), &parsed); err != nil {
+		return "", fmt.Errorf("%w: erro ao interpretar JSON do %s: %w", ErrConfiguracao, origem, err)
+	}
+
+	token := parsed[chave]
+	if token == "" {
+		return "", fmt.Errorf("%w: %s não contém %s", ErrConfiguracao, origem, chave)
+	}
+	return token, nil
+}
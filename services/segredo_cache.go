@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ChaveRejeitada é implementada pelas fontes de segredo que guardam a chave em
+// cache. O provedor chama ChaveRejeitada quando a API recusa chave, para que a
+// próxima chamada a APIKey busque a versão atual do segredo.
+type ChaveRejeitada interface {
+	ChaveRejeitada(chave string)
+}
+
+// EsperaSegredoPadrao é o CacheSegredo.Espera usado por NovoCacheSegredo.
+const EsperaSegredoPadrao = 30 * time.Second
+
+// CacheSegredo guarda em memória a chave obtida de Fonte por até TTL, evitando
+// uma consulta ao Secrets Manager por requisição. Cada leitura de Fonte tem
+// no máximo Prazo (zero para nenhum além do contexto recebido), e leituras
+// simultâneas viram uma só. Se a leitura falhar, a chave vencida continua em
+// uso, sem novas leituras, por mais Espera; sem chave, o mesmo erro é
+// devolvido nesse tempo. Da mesma forma, uma chave rejeitada só provoca nova
+// leitura uma vez a cada Espera. É seguro para uso concorrente.
+type CacheSegredo struct {
+	Fonte  SecretSource
+	TTL    time.Duration
+	Prazo  time.Duration
+	Espera time.Duration
+
+	agora     func() time.Time
+	grupo     singleflight.Group
+	mu        sync.Mutex
+	chave     string
+	erro      error
+	validade  time.Time
+	releitura time.Time
+}
+
+// NovoCacheSegredo cria o cache de fonte, com a chave valendo por ttl a partir
+// de quando é lida e Espera de EsperaSegredoPadrao.
+func NovoCacheSegredo(fonte SecretSource, ttl time.Duration, agora func() time.Time) *CacheSegredo {
+	return &CacheSegredo{Fonte: fonte, TTL: ttl, Espera: EsperaSegredoPadrao, agora: agora}
+}
+
+// APIKey devolve a chave em cache ou, se ela venceu ou foi rejeitada, lê a
+// chave de Fonte. Se a leitura falhar e ainda houver uma chave vencida, ela é
+// usada até a próxima tentativa, depois de Espera; sem chave, o erro da
+// leitura é devolvido até lá.
+func (c *CacheSegredo) APIKey(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	chave, erro, valida := c.chave, c.erro, c.agora().Before(c.validade)
+	c.mu.Unlock()
+	if valida && chave != "" {
+		return chave, nil
+	}
+	if valida && erro != nil {
+		return "", erro
+	}
+
+	chave, err := c.ler(ctx)
+	if err != nil && chave != "" {
+		slog.WarnContext(ctx, "erro ao renovar a chave do Fixer, usando a chave anterior", "erro", err, "espera", c.Espera.String())
+		return chave, nil
+	}
+	return chave, err
+}
+
+// ChaveRejeitada descarta chave do cache. Uma chave diferente da guardada
+// (já substituída por outra requisição) é ignorada, assim como rejeições
+// feitas a menos de Espera da anterior: se o segredo não foi rotacionado, a
+// releitura devolve a mesma chave, e relê-lo a cada requisição não adianta.
+func (c *CacheSegredo) ChaveRejeitada(chave string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	agora := c.agora()
+	if c.chave == chave && !agora.Before(c.releitura) {
+		c.chave = ""
+		c.releitura = agora.Add(c.Espera)
+	}
+}
+
+// Renovar lê a chave de Fonte e reinicia o TTL. Em caso de erro a chave
+// guardada é mantida.
+func (c *CacheSegredo) Renovar(ctx context.Context) error {
+	_, err := c.ler(ctx)
+	return err
+}
+
+// IniciarRenovacao chama Renovar a cada intervalo, em segundo plano, até ctx
+// ser cancelado. Assim rotações do segredo entram em vigor sem esperar o TTL.
+func (c *CacheSegredo) IniciarRenovacao(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Renovar(ctx); err != nil {
+					slog.ErrorContext(ctx, "erro ao renovar a chave do Fixer", "erro", err)
+				}
+			}
+		}
+	}()
+}
+
+// ler consulta Fonte sem segurar mu, em uma única leitura compartilhada pelas
+// chamadas simultâneas, e guarda a chave obtida. Se a leitura falhar, devolve
+// o erro com a chave guardada, se houver, e guarda o erro; a chave ou o erro
+// passam a valer por mais Espera. O cancelamento da leitura compartilhada
+// segue a regra de CotacaoService.AtualizarCotacoes.
+func (c *CacheSegredo) ler(ctx context.Context) (string, error) {
+	canal := c.grupo.DoChan("chave", func() (any, error) {
+		leitura := context.WithoutCancel(ctx)
+		if c.Prazo > 0 {
+			var cancelar context.CancelFunc
+			leitura, cancelar = context.WithTimeout(leitura, c.Prazo)
+			defer cancelar()
+		}
+
+		chave, err := c.Fonte.APIKey(leitura)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err != nil {
+			if espera := c.agora().Add(c.Espera); c.chave == "" || espera.After(c.validade) {
+				c.validade = espera
+			}
+			c.erro = err
+			return c.chave, err
+		}
+		c.chave, c.erro = chave, nil
+		c.validade = c.agora().Add(c.TTL)
+		return chave, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("leitura da chave do Fixer interrompida: %w", ctx.Err())
+	case resultado := <-canal:
+		return resultado.Val.(string), resultado.Err
+	}
+}
@@ -0,0 +1,230 @@
+package services_test
+
+import (
+	"cambio-brl-usd/services"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// segredoRotativo devolve chave e conta as leituras; err, se definido, faz a
+// leitura falhar.
+type segredoRotativo struct {
+	mu       sync.Mutex
+	chave    string
+	err      error
+	leituras int
+}
+
+func (s *segredoRotativo) APIKey(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leituras++
+	return s.chave, s.err
+}
+
+func (s *segredoRotativo) rotacionar(chave string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chave, s.err = chave, err
+}
+
+func (s *segredoRotativo) contagem() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leituras
+}
+
+// relogio é um time.Now controlado pelo teste.
+type relogio struct{ agora time.Time }
+
+func (r *relogio) Now() time.Time { return r.agora }
+
+func TestCacheSegredo_ReusaAteOTTL(t *testing.T) {
+	fonte := &segredoRotativo{chave: "v1"}
+	r := &relogio{agora: time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)}
+	cache := services.NovoCacheSegredo(fonte, time.Minute, r.Now)
+
+	for i := 0; i < 3; i++ {
+		chave, err := cache.APIKey(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", chave)
+	}
+	assert.Equal(t, 1, fonte.contagem())
+
+	fonte.rotacionar("v2", nil)
+	r.agora = r.agora.Add(time.Minute)
+
+	chave, err := cache.APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", chave)
+	assert.Equal(t, 2, fonte.contagem())
+}
+
+func TestCacheSegredo_ChaveRejeitada(t *testing.T) {
+	fonte := &segredoRotativo{chave: "v1"}
+	cache := services.NovoCacheSegredo(fonte, time.Hour, time.Now)
+	cache.APIKey(ctx)
+
+	fonte.rotacionar("v2", nil)
+	cache.ChaveRejeitada("antiga") // outra chave: ignorado
+	chave, _ := cache.APIKey(ctx)
+	assert.Equal(t, "v1", chave)
+
+	cache.ChaveRejeitada("v1")
+	chave, _ = cache.APIKey(ctx)
+	assert.Equal(t, "v2", chave)
+}
+
+func TestCacheSegredo_FalhaNaLeitura(t *testing.T) {
+	fonte := &segredoRotativo{err: services.ErrConfiguracao}
+	r := &relogio{agora: time.Now()}
+	cache := services.NovoCacheSegredo(fonte, time.Minute, r.Now)
+
+	_, err := cache.APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+
+	fonte.rotacionar("v1", nil)
+	r.agora = r.agora.Add(services.EsperaSegredoPadrao)
+	cache.APIKey(ctx)
+
+	// Vencida, mas a fonte está fora: mantém a chave anterior
+	fonte.rotacionar("", errors.New("timeout"))
+	r.agora = r.agora.Add(2 * time.Minute)
+	chave, err := cache.APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", chave)
+
+	// Rejeitada não é reaproveitada
+	cache.ChaveRejeitada("v1")
+	_, err = cache.APIKey(ctx)
+	assert.Error(t, err)
+}
+
+func TestCacheSegredo_FalhaNaLeituraEsperaAntesDeTentarDeNovo(t *testing.T) {
+	fonte := &segredoRotativo{chave: "v1"}
+	r := &relogio{agora: time.Now()}
+	cache := services.NovoCacheSegredo(fonte, time.Minute, r.Now)
+	cache.APIKey(ctx)
+
+	fonte.rotacionar("", errors.New("timeout"))
+	r.agora = r.agora.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		chave, err := cache.APIKey(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", chave)
+	}
+	assert.Equal(t, 2, fonte.contagem(), "só uma leitura depois da falha")
+
+	fonte.rotacionar("v2", nil)
+	r.agora = r.agora.Add(services.EsperaSegredoPadrao)
+	chave, _ := cache.APIKey(ctx)
+	assert.Equal(t, "v2", chave)
+	assert.Equal(t, 3, fonte.contagem())
+}
+
+func TestCacheSegredo_FalhaSemChaveRepeteOErroAteAEspera(t *testing.T) {
+	fonte := &segredoRotativo{err: errors.New("timeout")}
+	r := &relogio{agora: time.Now()}
+	cache := services.NovoCacheSegredo(fonte, time.Minute, r.Now)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.APIKey(ctx)
+		assert.EqualError(t, err, "timeout")
+	}
+	assert.Equal(t, 1, fonte.contagem(), "o erro é reaproveitado durante a espera")
+
+	fonte.rotacionar("v1", nil)
+	r.agora = r.agora.Add(services.EsperaSegredoPadrao)
+	chave, err := cache.APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", chave)
+	assert.Equal(t, 2, fonte.contagem())
+}
+
+func TestCacheSegredo_ChaveRejeitadaSemRotacaoEsperaAntesDeReler(t *testing.T) {
+	fonte := &segredoRotativo{chave: "v1"}
+	r := &relogio{agora: time.Now()}
+	cache := services.NovoCacheSegredo(fonte, time.Hour, r.Now)
+	cache.APIKey(ctx)
+
+	// Cada requisição recusada pelo Fixer rejeita a mesma chave
+	for i := 0; i < 3; i++ {
+		cache.ChaveRejeitada("v1")
+		chave, _ := cache.APIKey(ctx)
+		assert.Equal(t, "v1", chave)
+	}
+	assert.Equal(t, 2, fonte.contagem(), "só uma releitura por espera")
+
+	r.agora = r.agora.Add(services.EsperaSegredoPadrao)
+	cache.ChaveRejeitada("v1")
+	cache.APIKey(ctx)
+	assert.Equal(t, 3, fonte.contagem())
+}
+
+func TestCacheSegredo_LeiturasSimultaneasViramUma(t *testing.T) {
+	var leituras atomic.Int32
+	liberar := make(chan struct{})
+	fonte := services.SecretSourceFunc(func(context.Context) (string, error) {
+		leituras.Add(1)
+		<-liberar
+		return "v1", nil
+	})
+	cache := services.NovoCacheSegredo(fonte, time.Minute, time.Now)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chave, err := cache.APIKey(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", chave)
+		}()
+	}
+
+	// Quem desiste não fica preso atrás da leitura em andamento
+	assert.Eventually(t, func() bool { return leituras.Load() == 1 }, time.Second, time.Millisecond)
+	ctxCurto, cancelar := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelar()
+	_, err := cache.APIKey(ctxCurto)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(liberar)
+	wg.Wait()
+	assert.Equal(t, int32(1), leituras.Load())
+}
+
+func TestCacheSegredo_Renovacao(t *testing.T) {
+	fonte := &segredoRotativo{chave: "v1"}
+	cache := services.NovoCacheSegredo(fonte, time.Hour, time.Now)
+	cache.APIKey(ctx)
+
+	ctx, cancelar := context.WithCancel(context.Background())
+	defer cancelar()
+	fonte.rotacionar("v2", nil)
+	cache.IniciarRenovacao(ctx, time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		chave, _ := cache.APIKey(ctx)
+		return chave == "v2"
+	}, time.Second, time.Millisecond)
+}
+
+func TestCacheSegredo_PrazoDaLeitura(t *testing.T) {
+	fonte := services.SecretSourceFunc(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	cache := services.NovoCacheSegredo(fonte, time.Minute, time.Now)
+	cache.Prazo = 20 * time.Millisecond
+
+	_, err := cache.APIKey(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,178 @@
+package services_test
+
+import (
+	"cambio-brl-usd/config"
+	"cambio-brl-usd/services"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// secretsManagerFake responde a GetSecretValue com segredo ou err.
+type secretsManagerFake struct {
+	segredo string
+	err     error
+	pedido  string
+}
+
+func (f *secretsManagerFake) GetSecretValue(_ context.Context, in *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.pedido = aws.ToString(in.SecretId)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(f.segredo)}, nil
+}
+
+func TestSecretsManagerSource_APIKey(t *testing.T) {
+	fake := &secretsManagerFake{segredo: `{"fixer_api_key":"chave"}`}
+	source := &services.SecretsManagerSource{Client: fake, Nome: "fixer-api-key-dev"}
+
+	key, err := source.APIKey(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "chave", key)
+	assert.Equal(t, "fixer-api-key-dev", fake.pedido)
+}
+
+func TestBuscarAPIKeyDoFixer_ErroAoObterSegredo(t *testing.T) {
+	source := &services.SecretsManagerSource{Client: &secretsManagerFake{err: errors.New("erro simulado")}, Nome: "fixer-api-key-dev"}
+
+	key, err := source.APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+	if key != "" {
+		t.Errorf("Esperava string vazia, obteve: %s", key)
+	}
+}
+
+func TestBuscarAPIKeyDoFixer_ErroJSONUnmarshal(t *testing.T) {
+	for _, segredo := range []string{"isso-não-é-json", `{"fixer_api_key":123}`} {
+		source := &services.SecretsManagerSource{Client: &secretsManagerFake{segredo: segredo}, Nome: "fixer-api-key-dev"}
+
+		key, err := source.APIKey(ctx)
+		assert.ErrorIs(t, err, services.ErrConfiguracao)
+		if key != "" {
+			t.Errorf("Esperava string vazia por erro no unmarshal, obteve: %s", key)
+		}
+	}
+}
+
+func TestBuscarAPIKeyDoFixer_SegredoSemChave(t *testing.T) {
+	source := &services.SecretsManagerSource{Client: &secretsManagerFake{segredo: `{"outra":"x"}`}, Nome: "fixer-api-key-dev"}
+
+	_, err := source.APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+	assert.ErrorContains(t, err, "fixer_api_key")
+}
+
+func TestSecretsManagerSource_ChaveConfigurada(t *testing.T) {
+	source := services.NovoSecretsManagerSource(&secretsManagerFake{segredo: `{"apilayer":"outra"}`}, "fixer-api-key-dev")
+	source.Chave = "apilayer"
+
+	key, err := source.APIKey(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "outra", key)
+}
+
+// ssmFake responde a GetParameter com valor ou err.
+type ssmFake struct {
+	valor  string
+	err    error
+	pedido *ssm.GetParameterInput
+}
+
+func (f *ssmFake) GetParameter(_ context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	f.pedido = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Value: aws.String(f.valor)}}, nil
+}
+
+func TestSSMSource_APIKey(t *testing.T) {
+	fake := &ssmFake{valor: "chave\n"}
+	source := &services.SSMSource{Client: fake, Nome: "/cambio/fixer"}
+
+	key, err := source.APIKey(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "chave", key)
+	assert.Equal(t, "/cambio/fixer", aws.ToString(fake.pedido.Name))
+	assert.True(t, aws.ToBool(fake.pedido.WithDecryption))
+
+	source.Client = &ssmFake{valor: `{"fixer_api_key":"do-json"}`}
+	source.Chave = "fixer_api_key"
+	key, err = source.APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "do-json", key)
+
+	source.Client = &ssmFake{err: errors.New("ParameterNotFound")}
+	_, err = source.APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestEnvSource_APIKey(t *testing.T) {
+	t.Setenv("FIXER_API_KEY", "chave-local")
+
+	key, err := (&services.EnvSource{Variavel: "FIXER_API_KEY"}).APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "chave-local", key)
+
+	_, err = (&services.EnvSource{Variavel: "FIXER_API_KEY_INEXISTENTE"}).APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestArquivoSource_APIKey(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "fixer_api_key")
+	os.WriteFile(caminho, []byte(`{"key":"do-arquivo"}`), 0o600)
+
+	key, err := (&services.ArquivoSource{Caminho: caminho, Chave: "key"}).APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "do-arquivo", key)
+
+	// Rotação: o arquivo é relido a cada chamada
+	os.WriteFile(caminho, []byte("nova\n"), 0o600)
+	key, err = (&services.ArquivoSource{Caminho: caminho}).APIKey(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "nova", key)
+
+	_, err = (&services.ArquivoSource{Caminho: caminho + ".inexistente"}).APIKey(ctx)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
+
+func TestNovoSecretSource(t *testing.T) {
+	clientes, err := services.NovosClientesAWS(ctx, config.AWS{Regiao: "us-east-1"})
+	assert.NoError(t, err)
+
+	cfg := config.Padrao().Segredo
+	source, err := services.NovoSecretSource(cfg, clientes)
+	assert.NoError(t, err)
+	assert.Equal(t, "fixer_api_key", source.(*services.SecretsManagerSource).Chave)
+
+	cfg.ChaveJSON = "apilayer"
+	source, _ = services.NovoSecretSource(cfg, clientes)
+	assert.Equal(t, "apilayer", source.(*services.SecretsManagerSource).Chave)
+
+	for fonte, tipo := range map[string]any{
+		"ssm":  &services.SSMSource{},
+		"env":  &services.EnvSource{},
+		"file": &services.ArquivoSource{},
+	} {
+		cfg.Fonte = fonte
+		source, err := services.NovoSecretSource(cfg, clientes)
+		assert.NoError(t, err)
+		assert.IsType(t, tipo, source)
+	}
+
+	cfg.Fonte = "vault"
+	_, err = services.NovoSecretSource(cfg, clientes)
+	assert.ErrorIs(t, err, services.ErrConfiguracao)
+}
@@ -21,6 +21,9 @@ Resources:
         Cpu: "1 vCPU"
         Memory: "2 GB"
         InstanceRoleArn: ${aws_iam_role.apprunner_exec_role.arn}
+      HealthCheckConfiguration:
+        Protocol: HTTP
+        Path: /healthz
 
 Outputs:
   ServiceUrl:
@@ -1,3 +1,22 @@
+resource "aws_dynamodb_table" "cotacoes_por_par" {
+  name         = "CotacoesPorPar"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "par"
+  range_key    = "data_hora"
+
+  attribute {
+    name = "par"
+    type = "S"
+  }
+
+  attribute {
+    name = "data_hora"
+    type = "S"
+  }
+}
+
+# Tabela antiga, com data_hora como única chave. Mantida até que os itens
+# sejam copiados com `go run ./cmd/migrar`; pode ser removida depois disso.
 resource "aws_dynamodb_table" "cotacoes" {
   name         = "Cotacoes"
   billing_mode = "PAY_PER_REQUEST"
@@ -7,4 +26,4 @@ resource "aws_dynamodb_table" "cotacoes" {
     name = "data_hora"
     type = "S"
   }
-}
\ No newline at end of file
+}
@@ -1,3 +1,6 @@
+# O maior intervalo entre execuções (12h) define o padrão de
+# COTACAO_IDADE_MAXIMA e de READINESS_MAX_INGESTION_AGE (13h); ao mudar o
+# agendamento, ajuste as variáveis.
 resource "aws_cloudwatch_event_rule" "cotacao_agendada" {
   name                = "cotacao-agendada"
   schedule_expression = "cron(0 8,14,20 * * ? *)"
@@ -9,7 +9,7 @@ output "ecr_repo_lambda_url" {
 }
 
 output "dynamodb_table_name" {
-  value       = aws_dynamodb_table.cotacoes.name
+  value       = aws_dynamodb_table.cotacoes_por_par.name
   description = "Nome da tabela DynamoDB"
 }
 